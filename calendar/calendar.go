@@ -0,0 +1,265 @@
+// Package calendar parses the events of iCalendar(RFC 5545) files. It supports
+// the subset that the holiday and on-call calendars use. The recurring events
+// are expanded by the daily, weekly, monthly and yearly rules, and the other
+// rules are rejected.
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Event is a VEVENT of the calendar. End is exclusive. Attendees are the
+// email addresses of the ATTENDEE properties. Start and End of
+// the recurring event are the ones of the first occurrence.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Attendees   []string
+
+	rule         *rule
+	exdates      []time.Time
+	recurrenceID time.Time
+}
+
+// Contains reports whether the time is in the event or one of the
+// occurrences of the recurring event.
+func (e Event) Contains(t time.Time) bool {
+	_, ok := e.At(t)
+	return ok
+}
+
+// At returns the occurrence of the event that contains the time. The
+// occurrence of the recurring event has its own Start and End.
+func (e Event) At(t time.Time) (Event, bool) {
+	if e.rule == nil {
+		return e, e.contains(t)
+	}
+
+	return e.occurrence(t)
+}
+
+func (e Event) contains(t time.Time) bool {
+	return !t.Before(e.Start) && t.Before(e.End)
+}
+
+// Load parses the calendar file. The dates and the times without the
+// timezone are parsed in the location.
+func Load(path string, loc *time.Location) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f, loc)
+}
+
+// Parse parses the events of the calendar. The dates and the times without
+// the timezone are parsed in the location.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	var event *Event
+	var duration *string
+	for i, line := range lines {
+		name, params, value, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &Event{}
+			duration = nil
+		case name == "END" && value == "VEVENT":
+			if event == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", i+1)
+			}
+
+			if event.Start.IsZero() {
+				return nil, fmt.Errorf("line %d: event doesn't have DTSTART uid: %s", i+1, event.UID)
+			}
+
+			if event.End.IsZero() && duration != nil {
+				days, d, err := parseDuration(*duration)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v uid: %s", i+1, err, event.UID)
+				}
+				event.End = event.Start.AddDate(0, 0, days).Add(d)
+			}
+
+			if event.End.IsZero() {
+				event.End = event.Start
+				if event.AllDay {
+					event.End = event.Start.AddDate(0, 0, 1)
+				}
+			}
+
+			events = append(events, *event)
+			event = nil
+		case event == nil:
+		case name == "UID":
+			event.UID = value
+		case name == "SUMMARY":
+			event.Summary = unescape(value)
+		case name == "DESCRIPTION":
+			event.Description = unescape(value)
+		case name == "ATTENDEE":
+			if email := mailto(value); email != "" {
+				event.Attendees = append(event.Attendees, email)
+			}
+		case name == "DURATION":
+			duration = &value
+		case name == "RRULE":
+			if event.rule, err = parseRule(value, loc); err != nil {
+				return nil, fmt.Errorf("line %d: %v uid: %s", i+1, err, event.UID)
+			}
+		case name == "RDATE":
+			return nil, fmt.Errorf("line %d: RDATE is not supported uid: %s", i+1, event.UID)
+		case name == "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				t, _, err := parseTime(params, v, loc)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %v", i+1, err)
+				}
+				event.exdates = append(event.exdates, t)
+			}
+		case name == "RECURRENCE-ID":
+			if event.recurrenceID, _, err = parseTime(params, value, loc); err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+		case name == "DTSTART" || name == "DTEND":
+			t, allDay, err := parseTime(params, value, loc)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+
+			if name == "DTSTART" {
+				event.Start = t
+				event.AllDay = allDay
+			} else {
+				event.End = t
+			}
+		}
+	}
+
+	return excludeModified(events), nil
+}
+
+// excludeModified excludes the occurrences of the recurring events that are
+// replaced by the modified ones with the RECURRENCE-ID.
+func excludeModified(events []Event) []Event {
+	for _, modified := range events {
+		if modified.recurrenceID.IsZero() {
+			continue
+		}
+
+		for i := range events {
+			if events[i].UID == modified.UID && events[i].rule != nil {
+				events[i].exdates = append(events[i].exdates, modified.recurrenceID)
+			}
+		}
+	}
+
+	return events
+}
+
+// unfold joins the folded lines that start with a space or a tab.
+func unfold(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		if line == "" {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// parseLine splits the content line(e.g. `DTSTART;VALUE=DATE:20261019`) to
+// the name, the parameters and the value.
+func parseLine(line string) (string, map[string]string, string, error) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", nil, "", fmt.Errorf("content line doesn't have value: %s", line)
+	}
+
+	s := strings.Split(line[:i], ";")
+	params := map[string]string{}
+	for _, param := range s[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+
+	return strings.ToUpper(s[0]), params, line[i+1:], nil
+}
+
+func parseTime(params map[string]string, value string, loc *time.Location) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	if tzid, ok := params["TZID"]; ok {
+		tz, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("timezone is invalid tzid: %s", tzid)
+		}
+		loc = tz
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+func mailto(value string) string {
+	if !strings.HasPrefix(strings.ToLower(value), "mailto:") {
+		return ""
+	}
+
+	return value[len("mailto:"):]
+}
+
+var unescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescape(value string) string {
+	return unescaper.Replace(value)
+}
@@ -0,0 +1,94 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:holiday-1
+DTSTART;VALUE=DATE:20261103
+SUMMARY:Culture Day
+END:VEVENT
+BEGIN:VEVENT
+UID:oncall-1
+DTSTART:20261019T000000Z
+DTEND:20261020T000000Z
+SUMMARY:On-call\, primary
+ORGANIZER:mailto:lead@example.com
+ATTENDEE;CN=Alice;ROLE=REQ-PARTICIPANT:mailto:alice@
+ example.com
+END:VEVENT
+BEGIN:VEVENT
+UID:oncall-2
+DTSTART;TZID=Asia/Tokyo:20261020T090000
+DTEND;TZID=Asia/Tokyo:20261020T180000
+SUMMARY:Day shift
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestParse(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+
+	events, err := Parse(strings.NewReader(testCalendar), tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("event count doesn't match got: %d want: 3", len(events))
+	}
+
+	tcs := map[string]struct {
+		event Event
+		in    time.Time
+		out   time.Time
+	}{
+		"all day":  {events[0], time.Date(2026, 11, 3, 23, 59, 0, 0, tokyo), time.Date(2026, 11, 4, 0, 0, 0, 0, tokyo)},
+		"utc":      {events[1], time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC), time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC)},
+		"timezone": {events[2], time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if !tc.event.Contains(tc.in) {
+				t.Fatalf("event should contain %s", tc.in)
+			}
+
+			if tc.event.Contains(tc.out) {
+				t.Fatalf("event should not contain %s", tc.out)
+			}
+		})
+	}
+
+	if got := events[1].Summary; got != "On-call, primary" {
+		t.Fatalf("summary doesn't match got: %s", got)
+	}
+
+	if got := strings.Join(events[1].Attendees, ","); got != "alice@example.com" {
+		t.Fatalf("attendees don't match got: %s", got)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tcs := map[string]string{
+		"no dtstart":   "BEGIN:VEVENT\nUID:1\nEND:VEVENT\n",
+		"invalid date": "BEGIN:VEVENT\nDTSTART:2026-10-19\nEND:VEVENT\n",
+		"no value":     "BEGIN:VEVENT\nDTSTART\nEND:VEVENT\n",
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tc), time.UTC); err == nil {
+				t.Fatalf("test %s should fail", n)
+			}
+		})
+	}
+}
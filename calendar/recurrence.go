@@ -0,0 +1,254 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rule is the RRULE of the event. FREQ, INTERVAL, COUNT, UNTIL and BYDAY of
+// the weekly rules are supported.
+type rule struct {
+	freq      string
+	interval  int
+	count     int
+	until     time.Time
+	byDay     []time.Weekday
+	weekStart time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// parseRule parses the RRULE(e.g. `FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TU`). It
+// returns an error for the parts that are not supported so that the events
+// are never silently treated as a single occurrence.
+func parseRule(value string, loc *time.Location) (*rule, error) {
+	r := &rule{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("RRULE part is invalid: %s", part)
+		}
+
+		var err error
+		switch name, v := strings.ToUpper(kv[0]), kv[1]; name {
+		case "FREQ":
+			switch v {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = v
+			default:
+				return nil, fmt.Errorf("RRULE frequency is not supported: %s", v)
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(v)
+			if err == nil && r.interval < 1 {
+				err = fmt.Errorf("RRULE interval must be positive: %s", v)
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(v)
+			if err == nil && r.count < 1 {
+				err = fmt.Errorf("RRULE count must be positive: %s", v)
+			}
+		case "UNTIL":
+			r.until, _, err = parseTime(nil, v, loc)
+		case "BYDAY":
+			for _, day := range strings.Split(v, ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("RRULE BYDAY is not supported: %s", v)
+				}
+				r.byDay = append(r.byDay, weekday)
+			}
+		case "WKST":
+			weekday, ok := weekdays[v]
+			if !ok {
+				return nil, fmt.Errorf("RRULE WKST is invalid: %s", v)
+			}
+			r.weekStart = weekday
+		default:
+			return nil, fmt.Errorf("RRULE part is not supported: %s", name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.freq == "" {
+		return nil, fmt.Errorf("RRULE doesn't have FREQ: %s", value)
+	}
+
+	if len(r.byDay) > 0 && r.freq != "WEEKLY" {
+		return nil, fmt.Errorf("RRULE BYDAY is only supported for the weekly frequency: %s", value)
+	}
+
+	return r, nil
+}
+
+// period returns the beginning of the nth period of the rule from the start
+// and the starts of the occurrences in it. The occurrences that don't exist
+// (e.g. the 31st of the months that have 30 days) are skipped.
+func (r *rule) period(start time.Time, n int) (time.Time, []time.Time) {
+	n *= r.interval
+	switch r.freq {
+	case "DAILY":
+		t := start.AddDate(0, 0, n)
+		return t, []time.Time{t}
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			t := start.AddDate(0, 0, 7*n)
+			return t, []time.Time{t}
+		}
+
+		offset := (int(start.Weekday()) - int(r.weekStart) + 7) % 7
+		begin := start.AddDate(0, 0, 7*n-offset)
+		starts := []time.Time{}
+		for _, day := range r.byDay {
+			starts = append(starts, begin.AddDate(0, 0, (int(day)-int(r.weekStart)+7)%7))
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+		return begin, starts
+	case "MONTHLY":
+		t := start.AddDate(0, n, 0)
+		if t.Day() != start.Day() {
+			return t, nil
+		}
+		return t, []time.Time{t}
+	default:
+		t := start.AddDate(n, 0, 0)
+		if t.Day() != start.Day() {
+			return t, nil
+		}
+		return t, []time.Time{t}
+	}
+}
+
+// occurrence returns the occurrence of the recurring event that contains the
+// time.
+func (e Event) occurrence(t time.Time) (Event, bool) {
+	count := 0
+	for n := 0; ; n++ {
+		begin, starts := e.rule.period(e.Start, n)
+		if begin.After(t) {
+			return Event{}, false
+		}
+
+		for _, start := range starts {
+			if start.Before(e.Start) {
+				continue
+			}
+
+			if start.After(t) {
+				return Event{}, false
+			}
+
+			if !e.rule.until.IsZero() && start.After(e.rule.until) {
+				return Event{}, false
+			}
+
+			count++
+			if e.rule.count > 0 && count > e.rule.count {
+				return Event{}, false
+			}
+
+			if e.excluded(start) {
+				continue
+			}
+
+			occurrence := e
+			occurrence.Start = start
+			occurrence.End = e.endOf(start)
+			occurrence.rule = nil
+			if occurrence.contains(t) {
+				return occurrence, true
+			}
+		}
+	}
+}
+
+// endOf returns the end of the occurrence that starts at the time. The
+// all-day events keep the number of the days over the daylight saving time.
+func (e Event) endOf(start time.Time) time.Time {
+	if e.AllDay {
+		days := int(e.End.Sub(e.Start).Hours()/24 + 0.5)
+		return start.AddDate(0, 0, days)
+	}
+
+	return start.Add(e.End.Sub(e.Start))
+}
+
+func (e Event) excluded(start time.Time) bool {
+	for _, exdate := range e.exdates {
+		if exdate.Equal(start) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseDuration parses the DURATION(e.g. `P1D`, `PT8H30M`, `P1W`) to the
+// days and the time.
+func parseDuration(value string) (int, time.Duration, error) {
+	invalid := fmt.Errorf("duration is invalid: %s", value)
+	s := strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, 0, invalid
+	}
+
+	var days int
+	var d time.Duration
+	inTime := false
+	num := ""
+	for _, c := range s[1:] {
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+			continue
+		case c == 'T' && num == "" && !inTime:
+			inTime = true
+			continue
+		}
+
+		if num == "" {
+			return 0, 0, invalid
+		}
+
+		v, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, 0, invalid
+		}
+		num = ""
+
+		switch {
+		case c == 'W' && !inTime:
+			days += 7 * v
+		case c == 'D' && !inTime:
+			days += v
+		case c == 'H' && inTime:
+			d += time.Duration(v) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(v) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(v) * time.Second
+		default:
+			return 0, 0, invalid
+		}
+	}
+
+	if num != "" {
+		return 0, 0, invalid
+	}
+
+	return days, d, nil
+}
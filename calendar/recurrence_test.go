@@ -0,0 +1,145 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRecurringCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:new-year
+DTSTART;VALUE=DATE:20200101
+RRULE:FREQ=YEARLY
+SUMMARY:New Year's Day
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+DTSTART:20261005T090000Z
+DURATION:PT30M
+RRULE:FREQ=DAILY;COUNT=5
+EXDATE:20261007T090000Z
+SUMMARY:Standup
+END:VEVENT
+BEGIN:VEVENT
+UID:weekday-shift
+DTSTART:20261005T090000Z
+DTEND:20261005T180000Z
+RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20261031T000000Z
+SUMMARY:Weekday shift
+END:VEVENT
+BEGIN:VEVENT
+UID:weekday-shift
+RECURRENCE-ID:20261019T090000Z
+DTSTART:20261019T120000Z
+DTEND:20261019T180000Z
+SUMMARY:Weekday shift(late)
+END:VEVENT
+BEGIN:VEVENT
+UID:month-end
+DTSTART;VALUE=DATE:20260131
+RRULE:FREQ=MONTHLY
+SUMMARY:Month end
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestEvent_At(t *testing.T) {
+	events, err := Parse(strings.NewReader(testRecurringCalendar), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byUID := map[string]Event{}
+	for _, event := range events {
+		if event.recurrenceID.IsZero() {
+			byUID[event.UID] = event
+		}
+	}
+
+	tcs := map[string]struct {
+		event Event
+		at    time.Time
+		ok    bool
+		end   time.Time
+	}{
+		"yearly":                  {byUID["new-year"], time.Date(2027, 1, 1, 12, 0, 0, 0, time.UTC), true, time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC)},
+		"yearly the other day":    {byUID["new-year"], time.Date(2027, 1, 2, 0, 0, 0, 0, time.UTC), false, time.Time{}},
+		"before the first":        {byUID["new-year"], time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC), false, time.Time{}},
+		"duration":                {byUID["standup"], time.Date(2026, 10, 6, 9, 29, 0, 0, time.UTC), true, time.Date(2026, 10, 6, 9, 30, 0, 0, time.UTC)},
+		"after the duration":      {byUID["standup"], time.Date(2026, 10, 6, 9, 30, 0, 0, time.UTC), false, time.Time{}},
+		"excluded":                {byUID["standup"], time.Date(2026, 10, 7, 9, 10, 0, 0, time.UTC), false, time.Time{}},
+		"last of the count":       {byUID["standup"], time.Date(2026, 10, 9, 9, 10, 0, 0, time.UTC), true, time.Date(2026, 10, 9, 9, 30, 0, 0, time.UTC)},
+		"after the count":         {byUID["standup"], time.Date(2026, 10, 10, 9, 10, 0, 0, time.UTC), false, time.Time{}},
+		"by day":                  {byUID["weekday-shift"], time.Date(2026, 10, 7, 10, 0, 0, 0, time.UTC), true, time.Date(2026, 10, 7, 18, 0, 0, 0, time.UTC)},
+		"by day other day":        {byUID["weekday-shift"], time.Date(2026, 10, 6, 10, 0, 0, 0, time.UTC), false, time.Time{}},
+		"by day off week":         {byUID["weekday-shift"], time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC), false, time.Time{}},
+		"modified occurrence":     {byUID["weekday-shift"], time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), false, time.Time{}},
+		"after the until":         {byUID["weekday-shift"], time.Date(2026, 11, 2, 10, 0, 0, 0, time.UTC), false, time.Time{}},
+		"monthly":                 {byUID["month-end"], time.Date(2026, 3, 31, 10, 0, 0, 0, time.UTC), true, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		"monthly skips the month": {byUID["month-end"], time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC), false, time.Time{}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, ok := tc.event.At(tc.at)
+			if ok != tc.ok {
+				t.Fatalf("test %s occurrence doesn't match got: %v want: %v", n, ok, tc.ok)
+			}
+
+			if ok && !got.End.Equal(tc.end) {
+				t.Fatalf("end doesn't match got: %v want: %v", got.End, tc.end)
+			}
+		})
+	}
+}
+
+func TestParseRule_Invalid(t *testing.T) {
+	tcs := map[string]string{
+		"no freq":           "INTERVAL=2",
+		"hourly":            "FREQ=HOURLY",
+		"by month day":      "FREQ=MONTHLY;BYMONTHDAY=1",
+		"by day with count": "FREQ=MONTHLY;BYDAY=1MO",
+		"zero interval":     "FREQ=DAILY;INTERVAL=0",
+		"invalid until":     "FREQ=DAILY;UNTIL=tomorrow",
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if _, err := parseRule(tc, time.UTC); err == nil {
+				t.Fatalf("test %s should fail", n)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tcs := map[string]struct {
+		input   string
+		days    int
+		d       time.Duration
+		success bool
+	}{
+		"days":      {"P2D", 2, 0, true},
+		"weeks":     {"P1W", 7, 0, true},
+		"time":      {"PT8H30M", 0, 8*time.Hour + 30*time.Minute, true},
+		"both":      {"P1DT12H", 1, 12 * time.Hour, true},
+		"no unit":   {"P1", 0, 0, false},
+		"no period": {"1D", 0, 0, false},
+		"negative":  {"-P1D", 0, 0, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			days, d, err := parseDuration(tc.input)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if days != tc.days || d != tc.d {
+				t.Fatalf("duration doesn't match got: %d %v want: %d %v", days, d, tc.days, tc.d)
+			}
+		})
+	}
+}
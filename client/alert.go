@@ -0,0 +1,190 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/PagerDuty/go-pagerduty"
+	"go.uber.org/zap"
+)
+
+const defaultAlertSeverity = "error"
+
+// Alerter is a interface that the failure notification should implement
+type Alerter interface {
+	Trigger(group string, err error) error
+	Resolve(group string) error
+}
+
+var (
+	_ Alerter = (*slackAlerter)(nil)
+	_ Alerter = (*pagerdutyAlerter)(nil)
+)
+
+type slackAlerter struct {
+	channel string
+	slack   SlackClient
+}
+
+// NewSlackAlerter creates a alerter that posts the failures to the Slack channel.
+func NewSlackAlerter(slackClient SlackClient, channel string) Alerter {
+	return &slackAlerter{
+		channel: channel,
+		slack:   slackClient,
+	}
+}
+
+func (a *slackAlerter) Trigger(group string, err error) error {
+	text := fmt.Sprintf(":warning: Slackduty failed to synchronize group %s: %v", group, err)
+	return a.slack.PostMessage(a.channel, text)
+}
+
+func (a *slackAlerter) Resolve(group string) error {
+	text := fmt.Sprintf(":white_check_mark: Slackduty recovered synchronizing group %s", group)
+	return a.slack.PostMessage(a.channel, text)
+}
+
+type pagerdutyAlerter struct {
+	routingKey  string
+	severity    string
+	manageEvent func(pagerduty.V2Event) (*pagerduty.V2EventResponse, error)
+}
+
+// NewPagerdutyAlerter creates a alerter that triggers PagerDuty Events v2
+// alerts. Each group has its own dedup key so that the alert of the group is
+// resolved by the recovery.
+func NewPagerdutyAlerter(routingKey, severity string) Alerter {
+	if severity == "" {
+		severity = defaultAlertSeverity
+	}
+
+	return &pagerdutyAlerter{
+		routingKey:  routingKey,
+		severity:    severity,
+		manageEvent: pagerduty.ManageEvent,
+	}
+}
+
+func (a *pagerdutyAlerter) Trigger(group string, err error) error {
+	event := pagerduty.V2Event{
+		RoutingKey: a.routingKey,
+		Action:     "trigger",
+		DedupKey:   dedupKey(group),
+		Client:     "slackduty",
+		Payload: &pagerduty.V2Payload{
+			Summary:  fmt.Sprintf("Slackduty failed to synchronize group %s: %v", group, err),
+			Source:   "slackduty",
+			Severity: a.severity,
+			Group:    group,
+		},
+	}
+
+	_, err = a.manageEvent(event)
+	return err
+}
+
+func (a *pagerdutyAlerter) Resolve(group string) error {
+	event := pagerduty.V2Event{
+		RoutingKey: a.routingKey,
+		Action:     "resolve",
+		DedupKey:   dedupKey(group),
+	}
+
+	_, err := a.manageEvent(event)
+	return err
+}
+
+func dedupKey(group string) string {
+	return fmt.Sprintf("slackduty/%s", group)
+}
+
+// newAlerters creates the alerters configured in the config.
+func newAlerters(cfg *config.Alert, slackClient func(string) (SlackClient, error)) ([]Alerter, error) {
+	alerters := []Alerter{}
+	if cfg == nil {
+		return alerters, nil
+	}
+
+	if cfg.Slack != nil {
+		client, err := slackClient(cfg.Slack.Workspace)
+		if err != nil {
+			return nil, err
+		}
+
+		alerters = append(alerters, NewSlackAlerter(client, cfg.Slack.Channel))
+	}
+
+	if cfg.Pagerduty != nil {
+		alerters = append(alerters, NewPagerdutyAlerter(cfg.Pagerduty.RoutingKey, cfg.Pagerduty.Severity))
+	}
+
+	return alerters, nil
+}
+
+// lastAlert returns the error that the group is alerted with, or empty if the
+// group is not alerted. The last run in the state store is used when this
+// process hasn't synchronized the group yet(e.g. with external trigger).
+func (c *Client) lastAlert(group *config.Group) string {
+	name := group.Key()
+	c.alertMux.Lock()
+	alerted, ok := c.alerted[name]
+	c.alertMux.Unlock()
+	if ok || c.store == nil {
+		return alerted
+	}
+
+	records, err := c.store.History(name)
+	if err != nil {
+		c.logger.Warn("failed to load the history of the alert", zap.Error(err), zap.String("group", name))
+		return ""
+	}
+
+	if len(records) == 0 {
+		return ""
+	}
+
+	runID := records[len(records)-1].RunID
+	for _, record := range records {
+		if record.RunID == runID && record.Error != "" {
+			return record.Error
+		}
+	}
+
+	return ""
+}
+
+// alert triggers the alerts when the sync of the group starts failing or
+// fails with another error, and resolves them when it recovers. The previous
+// is the error that the group was alerted with. Failing to alert doesn't
+// change the result of the sync.
+func (c *Client) alert(group *config.Group, previous string, syncErr error) {
+	name := group.Key()
+	current := ""
+	if syncErr != nil {
+		current = syncErr.Error()
+	}
+
+	c.alertMux.Lock()
+	if c.alerted == nil {
+		c.alerted = map[string]string{}
+	}
+	c.alerted[name] = current
+	c.alertMux.Unlock()
+
+	if current == previous {
+		return
+	}
+
+	for _, alerter := range c.alerters {
+		var err error
+		if syncErr != nil {
+			err = alerter.Trigger(name, syncErr)
+		} else {
+			err = alerter.Resolve(name)
+		}
+
+		if err != nil {
+			c.logger.Warn("failed to send the alert", zap.Error(err), zap.String("group", name), zap.Bool("resolve", syncErr == nil))
+		}
+	}
+}
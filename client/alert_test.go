@@ -0,0 +1,85 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/KeisukeYamashita/slackduty/state"
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+func TestClient_Alert(t *testing.T) {
+	tcs := map[string]struct {
+		records []state.Record
+		errs    []string
+		want    int
+	}{
+		"success only":          {nil, []string{"", ""}, 0},
+		"failure":               {nil, []string{"test error"}, 1},
+		"failure and recovery":  {nil, []string{"test error", "", ""}, 2},
+		"repeated failure":      {nil, []string{"test error", "test error", ""}, 2},
+		"another failure":       {nil, []string{"test error", "another error"}, 2},
+		"failed last run":       {[]state.Record{{RunID: "1", Group: "test", Outcome: state.OutcomeFailed, Error: "test error"}}, []string{""}, 1},
+		"failed last run again": {[]state.Record{{RunID: "1", Group: "test", Outcome: state.OutcomeFailed, Error: "test error"}}, []string{"test error"}, 0},
+		"succeeded last run":    {[]state.Record{{RunID: "1", Group: "test", Outcome: state.OutcomeFailed, Error: "test error"}, {RunID: "2", Group: "test", Outcome: state.OutcomeSuccess}}, []string{""}, 0},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			c := &Client{
+				alerters: []Alerter{NewSlackAlerter(slackClient, "#ops")},
+				store:    &fakeStore{records: tc.records},
+				logger:   log.NewDiscard(),
+			}
+			group := &config.Group{Name: "test"}
+
+			for _, e := range tc.errs {
+				var err error
+				if e != "" {
+					err = errors.New(e)
+				}
+
+				c.alert(group, c.lastAlert(group), err)
+			}
+
+			if got := len(slackClient.messages["#ops"]); got != tc.want {
+				t.Fatalf("alert count doesn't match got: %d want: %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPagerdutyAlerter(t *testing.T) {
+	events := []pagerduty.V2Event{}
+	alerter := &pagerdutyAlerter{
+		routingKey: "test-routing-key",
+		severity:   defaultAlertSeverity,
+		manageEvent: func(e pagerduty.V2Event) (*pagerduty.V2EventResponse, error) {
+			events = append(events, e)
+			return &pagerduty.V2EventResponse{}, nil
+		},
+	}
+
+	if err := alerter.Trigger("test", errors.New("test error")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := alerter.Resolve("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("event count doesn't match got: %d want: 2", len(events))
+	}
+
+	if events[0].Action != "trigger" || events[1].Action != "resolve" {
+		t.Fatalf("event actions unexpected got: %s, %s", events[0].Action, events[1].Action)
+	}
+
+	if events[0].DedupKey != events[1].DedupKey {
+		t.Fatalf("dedup key should be the same got: %s, %s", events[0].DedupKey, events[1].DedupKey)
+	}
+}
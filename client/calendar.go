@@ -0,0 +1,103 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/calendar"
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"golang.org/x/sync/errgroup"
+)
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// calendarEmails returns the emails of the events of the calendar at the
+// time with the end of the events. The emails are the attendees and the ones
+// in the summary of the events(e.g. `Release captain: alice@example.com`).
+func calendarEmails(cal config.Calendar, t time.Time) (map[string]time.Time, error) {
+	loc := time.Local
+	if cal.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(cal.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone is invalid timezone: %s error: %v", cal.Timezone, err)
+		}
+	}
+
+	var match *regexp.Regexp
+	if cal.Match != "" {
+		var err error
+		match, err = regexp.Compile(cal.Match)
+		if err != nil {
+			return nil, fmt.Errorf("match is invalid match: %s error: %v", cal.Match, err)
+		}
+	}
+
+	events, err := calendar.Load(cal.Path, loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the calendar path: %s error: %v", cal.Path, err)
+	}
+
+	emails := map[string]time.Time{}
+	add := func(email string, end time.Time) {
+		email = strings.ToLower(email)
+		if end.After(emails[email]) {
+			emails[email] = end
+		}
+	}
+
+	for _, event := range events {
+		event, ok := event.At(t)
+		if !ok {
+			continue
+		}
+
+		if match != nil && !match.MatchString(event.Summary) {
+			continue
+		}
+
+		for _, attendee := range event.Attendees {
+			add(attendee, event.End)
+		}
+
+		for _, email := range emailPattern.FindAllString(event.Summary, -1) {
+			add(email, event.End)
+		}
+	}
+
+	return emails, nil
+}
+
+// getCalendarMembers resolves the emails of the events at the execution time
+// to the Slack users.
+func (c *Client) getCalendarMembers(slackClient SlackClient, calendars []config.Calendar, members *slackduty.Members) error {
+	now := c.clock()
+	eg := errgroup.Group{}
+	for _, cal := range calendars {
+		cal := cal
+		eg.Go(func() error {
+			emails, err := calendarEmails(cal, now)
+			if err != nil {
+				return err
+			}
+
+			for email, end := range emails {
+				slackUser, err := slackClient.GetUser(fmt.Sprintf("email:%s", email))
+				if err != nil {
+					return err
+				}
+
+				member := convSlackUser(slackUser, email)
+				member.Until = end
+				members.AddFrom(calendarSource(cal.Path), member)
+			}
+
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/slack-go/slack"
+)
+
+const rotationCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:captain-1
+SUMMARY:Release captain: Alice@example.com
+DTSTART;VALUE=DATE:20200601
+DTEND;VALUE=DATE:20200608
+END:VEVENT
+BEGIN:VEVENT
+UID:captain-2
+SUMMARY:Release captain: bob@example.com
+DTSTART;VALUE=DATE:20200608
+DTEND;VALUE=DATE:20200615
+END:VEVENT
+BEGIN:VEVENT
+UID:vacation-1
+SUMMARY:Vacation
+DTSTART:20200603T000000Z
+DTEND:20200605T000000Z
+ORGANIZER;CN=Lead:mailto:lead@example.com
+ATTENDEE;CN=Carol:mailto:carol@example.com
+END:VEVENT
+BEGIN:VEVENT
+UID:support-1
+SUMMARY:Support
+DTSTART:20200506T090000Z
+DTEND:20200506T180000Z
+RRULE:FREQ=WEEKLY
+ATTENDEE;CN=Dave:mailto:dave@example.com
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestGetMembers_Calendars(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackduty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rotation.ics")
+	if err := ioutil.WriteFile(path, []byte(rotationCalendar), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+	slackClient.users["email:carol@example.com"] = &slack.User{ID: "U3"}
+	slackClient.users["email:dave@example.com"] = &slack.User{ID: "U4"}
+	slackClient.users["email:lead@example.com"] = &slack.User{ID: "U5"}
+
+	now := time.Date(2020, 6, 3, 12, 0, 0, 0, time.UTC)
+	c := &Client{slack: slackClient, now: func() time.Time { return now }, logger: log.NewDiscard()}
+
+	tcs := map[string]struct {
+		calendar config.Calendar
+		want     []string
+		success  bool
+	}{
+		"all events":       {config.Calendar{Path: path, Timezone: "UTC"}, []string{"U1", "U3", "U4"}, true},
+		"match":            {config.Calendar{Path: path, Match: "^Release captain", Timezone: "UTC"}, []string{"U1"}, true},
+		"attendees":        {config.Calendar{Path: path, Match: "Vacation", Timezone: "UTC"}, []string{"U3"}, true},
+		"recurring":        {config.Calendar{Path: path, Match: "Support", Timezone: "UTC"}, []string{"U4"}, true},
+		"invalid match":    {config.Calendar{Path: path, Match: "("}, nil, false},
+		"invalid timezone": {config.Calendar{Path: path, Timezone: "Mars/Olympus"}, nil, false},
+		"missing file":     {config.Calendar{Path: filepath.Join(dir, "missing.ics")}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			members, err := c.GetMembers(slackClient, &config.Members{Calendars: []config.Calendar{tc.calendar}})
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if !tc.success {
+				return
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+
+	emails, err := calendarEmails(config.Calendar{Path: path, Match: "Support", Timezone: "UTC"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := time.Date(2020, 6, 3, 18, 0, 0, 0, time.UTC); !emails["dave@example.com"].Equal(want) {
+		t.Fatalf("end of the occurrence doesn't match got: %v want: %v", emails["dave@example.com"], want)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/KeisukeYamashita/slackduty/config"
 	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"github.com/KeisukeYamashita/slackduty/state"
 	"github.com/robfig/cron"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -16,16 +18,57 @@ import (
 
 // Client acts likes a manager of the jobs.
 type Client struct {
+	alerted         map[string]string
+	alertMux        sync.Mutex
+	alerters        []Alerter
 	config          *config.Config
 	externalTrigger bool
+	force           bool
+	forced          map[string]bool
 	cron            *cron.Cron
+	groupMux        sync.Mutex
+	groupLocks      map[string]*sync.Mutex
+	now             func() time.Time
 	pagerduty       PagerdutyClient
+	accounts        map[string]PagerdutyClient
+	opsgenie        OpsgenieClient
+	opsgenies       map[string]OpsgenieClient
 	slack           SlackClient
+	store           state.Store
+	workspaces      map[string]SlackClient
 	logger          *zap.Logger
 }
 
+const (
+	defaultAccount   = "default"
+	defaultWorkspace = "default"
+)
+
 type options struct {
+	accounts        []account
 	externalTrigger bool
+	force           bool
+	opsgenie        *opsgenieAccount
+	opsgenies       []opsgenieAccount
+	store           state.Store
+	workspaces      []workspace
+}
+
+type account struct {
+	name   string
+	apiKey string
+}
+
+type opsgenieAccount struct {
+	name   string
+	apiKey string
+	url    string
+}
+
+type workspace struct {
+	name   string
+	apiKey string
+	teamID string
 }
 
 // ClientOption are options that developers can configure for the
@@ -40,8 +83,59 @@ func WithExternalTrigger() ClientOption {
 	}
 }
 
-// New creates a Client for Slack & PagerDuty API.
-func New(cfg *config.Config, pdAPIKey, slackAPIKey string, logger *zap.Logger, opts ...ClientOption) *Client {
+// WithForce disables the guards of the groups for the next run of each group
+// so that the sync is applied even if the members violate them.
+func WithForce() ClientOption {
+	return func(o *options) {
+		o.force = true
+	}
+}
+
+// WithPagerdutyAccount adds a named PagerDuty account that the PagerDuty
+// members of the groups can refer by the account.
+func WithPagerdutyAccount(name, apiKey string) ClientOption {
+	return func(o *options) {
+		o.accounts = append(o.accounts, account{name: name, apiKey: apiKey})
+	}
+}
+
+// WithOpsgenie configures the default Opsgenie account that the Opsgenie
+// members of the groups are resolved from. The default URL is used if the url
+// is empty.
+func WithOpsgenie(apiKey, url string) ClientOption {
+	return func(o *options) {
+		o.opsgenie = &opsgenieAccount{apiKey: apiKey, url: url}
+	}
+}
+
+// WithOpsgenieAccount adds a named Opsgenie account that the Opsgenie members
+// of the groups can refer by the account. The default URL is used if the url
+// is empty.
+func WithOpsgenieAccount(name, apiKey, url string) ClientOption {
+	return func(o *options) {
+		o.opsgenies = append(o.opsgenies, opsgenieAccount{name: name, apiKey: apiKey, url: url})
+	}
+}
+
+// WithStore records the result of the syncs to the state store.
+func WithStore(store state.Store) ClientOption {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithWorkspace adds a named Slack workspace that groups can synchronize.
+// The teamID is required for workspace-scoped usergroups when the API key is
+// an Enterprise Grid organization token, otherwise leave it empty.
+func WithWorkspace(name, apiKey, teamID string) ClientOption {
+	return func(o *options) {
+		o.workspaces = append(o.workspaces, workspace{name: name, apiKey: apiKey, teamID: teamID})
+	}
+}
+
+// New creates a Client for Slack & PagerDuty API. It fails if the alerts
+// can't be configured.
+func New(cfg *config.Config, pdAPIKey, slackAPIKey string, logger *zap.Logger, opts ...ClientOption) (*Client, error) {
 	var o options
 	for _, opt := range opts {
 		opt(&o)
@@ -50,11 +144,38 @@ func New(cfg *config.Config, pdAPIKey, slackAPIKey string, logger *zap.Logger, o
 	pdClient := NewPagerDutyClient(pdAPIKey)
 	slackClient := NewSlackClient(slackAPIKey)
 	c := &Client{
-		config:    cfg,
-		pagerduty: pdClient,
-		slack:     slackClient,
-		logger:    logger,
+		accounts:   map[string]PagerdutyClient{},
+		config:     cfg,
+		force:      o.force,
+		opsgenies:  map[string]OpsgenieClient{},
+		pagerduty:  pdClient,
+		slack:      slackClient,
+		store:      o.store,
+		workspaces: map[string]SlackClient{},
+		logger:     logger,
+	}
+
+	for _, acc := range o.accounts {
+		c.accounts[acc.name] = NewPagerDutyClient(acc.apiKey)
+	}
+
+	if o.opsgenie != nil {
+		c.opsgenie = o.opsgenie.client()
+	}
+
+	for _, acc := range o.opsgenies {
+		c.opsgenies[acc.name] = acc.client()
+	}
+
+	for _, ws := range o.workspaces {
+		c.workspaces[ws.name] = NewSlackClient(ws.apiKey, WithTeamID(ws.teamID))
+	}
+
+	alerters, err := newAlerters(cfg.Alert, c.slackClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the alerts: %v", err)
 	}
+	c.alerters = alerters
 
 	if o.externalTrigger {
 		c.externalTrigger = o.externalTrigger
@@ -62,7 +183,7 @@ func New(cfg *config.Config, pdAPIKey, slackAPIKey string, logger *zap.Logger, o
 		c.cron = cron.New()
 	}
 
-	return c
+	return c, nil
 }
 
 // Run the job.
@@ -106,55 +227,519 @@ func (c *Client) Run() error {
 	return nil
 }
 
+// pagerdutyClient returns the PagerDuty client of the account. The default
+// account is the one configured by SLACKDUTY_PAGERDUTY_API_KEY.
+func (c *Client) pagerdutyClient(name string) (PagerdutyClient, error) {
+	if name == "" || name == defaultAccount {
+		return c.pagerduty, nil
+	}
+
+	pdClient, ok := c.accounts[name]
+	if !ok {
+		return nil, fmt.Errorf("pagerduty account is not configured account: %s", name)
+	}
+
+	return pdClient, nil
+}
+
+// opsgenieClient returns the Opsgenie client of the account. The default
+// account is the one configured by `opsgenie` of the config.
+func (c *Client) opsgenieClient(name string) (OpsgenieClient, error) {
+	if name == "" || name == defaultAccount {
+		if c.opsgenie == nil {
+			return nil, errors.New("opsgenie is not configured")
+		}
+
+		return c.opsgenie, nil
+	}
+
+	ogClient, ok := c.opsgenies[name]
+	if !ok {
+		return nil, fmt.Errorf("opsgenie account is not configured account: %s", name)
+	}
+
+	return ogClient, nil
+}
+
+// slackClient returns the Slack client of the workspace. The default
+// workspace is the one configured by SLACKDUTY_SLACK_API_KEY.
+func (c *Client) slackClient(name string) (SlackClient, error) {
+	if name == "" || name == defaultWorkspace {
+		return c.slack, nil
+	}
+
+	slackClient, ok := c.workspaces[name]
+	if !ok {
+		return nil, fmt.Errorf("slack workspace is not configured workspace: %s", name)
+	}
+
+	return slackClient, nil
+}
+
+// apiKeySetter is implemented by the clients whose API key can be rotated.
+type apiKeySetter interface {
+	SetAPIKey(string)
+}
+
+// SetOpsgenieAPIKey rotates the API key of the Opsgenie account.
+func (c *Client) SetOpsgenieAPIKey(account, apiKey string) error {
+	ogClient, err := c.opsgenieClient(account)
+	if err != nil {
+		return err
+	}
+
+	setter, ok := ogClient.(apiKeySetter)
+	if !ok {
+		return errors.New("opsgenie client doesn't support rotating the API key")
+	}
+
+	setter.SetAPIKey(apiKey)
+	return nil
+}
+
+// SetPagerdutyAPIKey rotates the API key of the PagerDuty account.
+func (c *Client) SetPagerdutyAPIKey(account, apiKey string) error {
+	pdClient, err := c.pagerdutyClient(account)
+	if err != nil {
+		return err
+	}
+
+	setter, ok := pdClient.(apiKeySetter)
+	if !ok {
+		return fmt.Errorf("pagerduty client doesn't support rotating the API key account: %s", account)
+	}
+
+	setter.SetAPIKey(apiKey)
+	return nil
+}
+
+// SetSlackAPIKey rotates the API key of the Slack workspace.
+func (c *Client) SetSlackAPIKey(workspace, apiKey string) error {
+	slackClient, err := c.slackClient(workspace)
+	if err != nil {
+		return err
+	}
+
+	setter, ok := slackClient.(apiKeySetter)
+	if !ok {
+		return fmt.Errorf("slack client doesn't support rotating the API key workspace: %s", workspace)
+	}
+
+	setter.SetAPIKey(apiKey)
+	return nil
+}
+
+// configureGroup synchronizes the group and alerts the result to the
+// configured alerters.
 func (c *Client) configureGroup(group *config.Group) error {
+	lock := c.groupLock(group)
+	lock.Lock()
+	defer lock.Unlock()
+
+	previous := c.lastAlert(group)
+	err := c.syncGroup(group)
+	c.alert(group, previous, err)
+	return err
+}
+
+// groupLock returns the lock of the group so that the scheduled job and the
+// file watcher don't sync the same group at once.
+func (c *Client) groupLock(group *config.Group) *sync.Mutex {
+	c.groupMux.Lock()
+	defer c.groupMux.Unlock()
+
+	if c.groupLocks == nil {
+		c.groupLocks = map[string]*sync.Mutex{}
+	}
+
+	lock, ok := c.groupLocks[group.Key()]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.groupLocks[group.Key()] = lock
+	}
+
+	return lock
+}
+
+// forcing reports whether the guards are disabled for the next run of the
+// group.
+func (c *Client) forcing(group *config.Group) bool {
+	c.groupMux.Lock()
+	defer c.groupMux.Unlock()
+
+	return c.force && !c.forced[group.Key()]
+}
+
+// takeForce reports whether the guards are disabled for this run of the group
+// and uses it up. The force applies only to the next run of each group so that
+// the cronjob doesn't keep the guards disabled.
+func (c *Client) takeForce(group *config.Group) bool {
+	c.groupMux.Lock()
+	defer c.groupMux.Unlock()
+
+	if !c.force || c.forced[group.Key()] {
+		return false
+	}
+
+	if c.forced == nil {
+		c.forced = map[string]bool{}
+	}
+	c.forced[group.Key()] = true
+
+	c.logger.Warn("the guards of the group are disabled for this run by the force", zap.String("group", group.Name))
+	return true
+}
+
+func (c *Client) syncGroup(group *config.Group) error {
 	c.logger.Info("start to run configure group job", zap.String("name", group.Name), zap.String("schedule", group.Schedule))
 
-	if err := c.preCheck(group); err != nil {
-		c.logger.Error("precheck failed", zap.Error(err), zap.String("group", group.Name), zap.String("schedule", group.Schedule))
+	pause, err := c.paused(group)
+	if err != nil {
+		c.logger.Error("failed to get the pause of the group", zap.Error(err), zap.String("group", group.Name))
+		return err
+	}
+
+	if pause != nil {
+		c.logger.Warn("the group is paused, skip the sync", zap.String("group", group.Name), zap.String("reason", pause.Reason), zap.String("run id", pause.RunID), zap.Time("paused at", pause.Timestamp))
+		return nil
+	}
+
+	workspaces := group.Workspaces
+	if len(workspaces) == 0 {
+		workspaces = []string{defaultWorkspace}
+	}
+
+	// Note: The workspaces are synchronized independently so that a failure of
+	// a workspace doesn't leave the others out of date.
+	force := c.takeForce(group)
+	runID := state.NewRunID(c.clock())
+	failed := map[string]error{}
+	for _, workspace := range workspaces {
+		slackClient, err := c.slackClient(workspace)
+		if err != nil {
+			c.logger.Error("failed to get the Slack workspace", zap.Error(err), zap.String("group", group.Name), zap.String("workspace", workspace))
+			failed[workspace] = err
+			continue
+		}
+
+		if err := c.syncWorkspace(slackClient, group, workspace, runID, force); err != nil {
+			failed[workspace] = err
+		}
+	}
+
+	return workspacesError(workspaces, failed)
+}
+
+// workspacesError returns the error of the failed workspaces. The error of the
+// workspace is returned as it is if only one workspace failed.
+func workspacesError(workspaces []string, failed map[string]error) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	msgs := []string{}
+	for _, workspace := range workspaces {
+		err, ok := failed[workspace]
+		if !ok {
+			continue
+		}
+
+		if len(failed) == 1 {
+			return err
+		}
+
+		msgs = append(msgs, fmt.Sprintf("workspace: %s error: %v", workspace, err))
+	}
+
+	return fmt.Errorf("failed to synchronize %d workspaces: %s", len(failed), strings.Join(msgs, ", "))
+}
+
+// syncWorkspace synchronizes the usergroups of the group in a single Slack
+// workspace. Members are resolved per workspace because the Slack user IDs
+// differ between workspaces.
+func (c *Client) syncWorkspace(slackClient SlackClient, group *config.Group, workspace, runID string, force bool) (err error) {
+	// Note: pending is the usergroups that are not updated yet. They are
+	// recorded as failed or held if the sync fails.
+	pending := group.Usergroups
+	defer func() {
+		if err != nil {
+			outcome := state.OutcomeFailed
+			var gErr *guardError
+			if errors.As(err, &gErr) {
+				outcome = state.OutcomeHeld
+			}
+
+			for _, usergroup := range pending {
+				c.record(group, workspace, usergroup, runID, outcome, nil, err)
+			}
+		}
+	}()
+
+	disabled, err := c.preCheck(slackClient, group)
+	if err != nil {
+		c.logger.Error("precheck failed", zap.Error(err), zap.String("group", group.Name), zap.String("schedule", group.Schedule), zap.String("workspace", workspace))
 		return fmt.Errorf("precheck failed error: %v", err)
 	}
 
-	members, err := c.GetMembers(group.Members)
+	outside, err := disabledHours(group, c.clock())
 	if err != nil {
-		c.logger.Error("failed to get members of the group", zap.Error(err), zap.String("group", group.Name), zap.String("schedule", group.Schedule))
 		return err
 	}
 
-	members, err = members.Filter(group.Exclude)
+	if outside {
+		if err := c.disableUsergroups(slackClient, group, workspace, runID, disabled); err != nil {
+			return err
+		}
+
+		pending = nil
+		return nil
+	}
+
+	members, clear, err := c.resolveMembers(slackClient, group, workspace)
 	if err != nil {
-		c.logger.Error("failed to filter the members of the group", zap.Error(err), zap.String("group", group.Name), zap.String("schedule", group.Schedule))
 		return err
 	}
 
-	if len(members.Members) == 0 {
-		c.logger.Warn("no member was in the member", zap.String("group", group.Name), zap.String("schedule", group.Schedule))
+	if len(members.Members) == 0 && !clear {
+		for _, usergroup := range group.Usergroups {
+			c.record(group, workspace, usergroup, runID, state.OutcomeSkipped, members, nil)
+		}
 		return nil
 	}
 
-	for _, usergroup := range group.Usergroups {
-		if err := c.updateUsergroup(usergroup, members.Members); err != nil {
-			c.logger.Error("failed to update the Slack usergroup", zap.Error(err), zap.String("group", group.Name))
+	for i, usergroup := range group.Usergroups {
+		pending = group.Usergroups[i:]
+
+		guard := group.Guard
+		if force {
+			guard = nil
+		}
+
+		var current []string
+		if group.Notify != nil || guard != nil {
+			current, err = c.currentMembers(slackClient, group, workspace, usergroup)
+			if err != nil {
+				c.logger.Error("failed to get the current members of the Slack usergroup", zap.Error(err), zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
+				return err
+			}
+		}
+
+		if err := checkGuard(guard, current, members.Members); err != nil {
+			c.logger.Warn("the sync is held back by the guard", zap.Error(err), zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
 			return err
 		}
 
-		c.logger.Info("updated a slack usergroup", zap.String("group", group.Name), zap.String("schedule", group.Schedule), zap.String("usergroup", usergroup))
+		if clear {
+			if disabled[usergroup] {
+				c.record(group, workspace, usergroup, runID, state.OutcomeCleared, members, nil)
+				continue
+			}
+
+			if err := slackClient.DisableUsergroup(usergroup); err != nil {
+				c.logger.Error("failed to disable the Slack usergroup", zap.Error(err), zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
+				return err
+			}
+
+			if group.Notify != nil {
+				_, removed := slackduty.Diff(current, nil)
+				c.notifyMembers(slackClient, group, usergroup, nil, nil, removed)
+			}
+
+			c.record(group, workspace, usergroup, runID, state.OutcomeCleared, members, nil)
+			c.logger.Info("disabled a slack usergroup because no member was resolved", zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
+			continue
+		}
+
+		if disabled[usergroup] {
+			if err := slackClient.EnableUsergroup(usergroup); err != nil {
+				c.logger.Error("failed to enable the Slack usergroup", zap.Error(err), zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
+				return err
+			}
+
+			c.logger.Info("enabled a slack usergroup", zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
+		}
+
+		if err := c.updateUsergroup(slackClient, usergroup, members.Members); err != nil {
+			c.logger.Error("failed to update the Slack usergroup", zap.Error(err), zap.String("group", group.Name), zap.String("workspace", workspace))
+			return err
+		}
+
+		if group.Notify != nil {
+			added, removed := slackduty.Diff(current, members.Members)
+			c.notifyMembers(slackClient, group, usergroup, members.Members, added, removed)
+		}
+
+		c.record(group, workspace, usergroup, runID, state.OutcomeSuccess, members, nil)
+		c.logger.Info("updated a slack usergroup", zap.String("group", group.Name), zap.String("schedule", group.Schedule), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
 	}
 
+	pending = nil
 	return nil
 }
 
+// resolveMembers resolves the members of the group in the workspace. The
+// members are selected by the rules, filtered by the exclude and the
+// overrides are applied. If nobody is resolved, the on_empty policy is
+// applied and clear reports whether the usergroups should be disabled.
+func (c *Client) resolveMembers(slackClient SlackClient, group *config.Group, workspace string) (members *slackduty.Members, clear bool, err error) {
+	rule, cfg, err := activeMembers(group, c.clock())
+	if err != nil {
+		c.logger.Error("failed to select the members of the group", zap.Error(err), zap.String("group", group.Name), zap.String("workspace", workspace))
+		return nil, false, err
+	}
+
+	if rule != "" {
+		c.logger.Info("the rule of the group is active", zap.String("group", group.Name), zap.String("rule", rule), zap.String("workspace", workspace))
+	}
+
+	members, err = c.GetMembers(slackClient, cfg)
+	if err != nil {
+		c.logger.Error("failed to get members of the group", zap.Error(err), zap.String("group", group.Name), zap.String("schedule", group.Schedule), zap.String("workspace", workspace))
+		return nil, false, err
+	}
+
+	members, err = c.refineMembers(slackClient, group, workspace, members)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(members.Members) == 0 {
+		policy, err := onEmpty(group)
+		if err != nil {
+			return nil, false, err
+		}
+
+		c.logger.Warn("no member was in the member", zap.String("group", group.Name), zap.String("schedule", group.Schedule), zap.String("workspace", workspace), zap.String("on empty", policy))
+		switch policy {
+		case onEmptyFallback:
+			members, err = c.GetMembers(slackClient, group.Fallback)
+			if err != nil {
+				c.logger.Error("failed to get the fallback members of the group", zap.Error(err), zap.String("group", group.Name), zap.String("workspace", workspace))
+				return nil, false, err
+			}
+
+			members, err = c.refineMembers(slackClient, group, workspace, members)
+			if err != nil {
+				return nil, false, err
+			}
+		case onEmptyClear:
+			clear = true
+		}
+	}
+
+	for _, member := range members.Members {
+		c.logger.Info("resolved a member", zap.String("group", group.Name), zap.String("workspace", workspace), zap.String("id", member.ID), zap.String("email", member.Email), zap.Strings("sources", member.Sources))
+	}
+
+	return members, clear, nil
+}
+
+// refineMembers applies the excludes, the unavailability and the overrides of
+// the group to the members. The fallback members are refined in the same way.
+func (c *Client) refineMembers(slackClient SlackClient, group *config.Group, workspace string, resolved *slackduty.Members) (*slackduty.Members, error) {
+	members, err := resolved.Filter(group.Exclude)
+	if err != nil {
+		c.logger.Error("failed to filter the members of the group", zap.Error(err), zap.String("group", group.Name), zap.String("schedule", group.Schedule), zap.String("workspace", workspace))
+		return nil, err
+	}
+
+	for _, member := range resolved.Subtract(members).Members {
+		member.RemovedBy = "exclude"
+		members.Removed = append(members.Removed, member)
+	}
+
+	if err := c.excludeUnavailable(slackClient, group, members); err != nil {
+		c.logger.Error("failed to exclude the unavailable members of the group", zap.Error(err), zap.String("group", group.Name), zap.String("workspace", workspace))
+		return nil, err
+	}
+
+	if err := c.applyOverrides(slackClient, group, members); err != nil {
+		c.logger.Error("failed to apply the overrides of the group", zap.Error(err), zap.String("group", group.Name), zap.String("schedule", group.Schedule), zap.String("workspace", workspace))
+		return nil, err
+	}
+
+	if err := c.excludeAccounts(slackClient, group, members); err != nil {
+		c.logger.Error("failed to exclude the members by the account", zap.Error(err), zap.String("group", group.Name), zap.String("workspace", workspace))
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// disableUsergroups disables the usergroups of the group outside of the
+// configured hours. The disabled usergroups are left as they are.
+func (c *Client) disableUsergroups(slackClient SlackClient, group *config.Group, workspace, runID string, disabled map[string]bool) error {
+	for _, usergroup := range group.Usergroups {
+		if !disabled[usergroup] {
+			if err := slackClient.DisableUsergroup(usergroup); err != nil {
+				c.logger.Error("failed to disable the Slack usergroup", zap.Error(err), zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
+				return err
+			}
+
+			c.logger.Info("disabled a slack usergroup outside of the hours", zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
+		}
+
+		c.record(group, workspace, usergroup, runID, state.OutcomeDisabled, nil, nil)
+	}
+
+	return nil
+}
+
+// clock returns the current time. It can be replaced in the tests.
+func (c *Client) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
+
 // GetMembers get all members that should be a member of the usergroup(s)
-// For can specify Slack user and Pagerduty users, teams, services and also
-// schedules.
-func (c *Client) GetMembers(cfg *config.Members) (*slackduty.Members, error) {
+// For can specify Slack user, Pagerduty users, teams, services and also
+// schedules, Opsgenie users, teams and schedules, the calendars and the roster
+// files.
+func (c *Client) GetMembers(slackClient SlackClient, cfg *config.Members) (*slackduty.Members, error) {
 	members := &slackduty.Members{}
+	if cfg == nil {
+		return members, nil
+	}
 	eg := errgroup.Group{}
 
-	if cfg.Pagerduty != nil {
+	sources, err := c.sources(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		source := source
 		eg.Go(func() error {
-			err := c.getPagerDutyMembers(cfg.Pagerduty, members)
+			err := source.Members(slackClient, members)
 			if err != nil {
-				c.logger.Error("failed to get PagerDuty members", zap.Error(err))
+				c.logger.Error("failed to get the members of the source", zap.Error(err), zap.String("source", source.Name()))
+				return err
+			}
+
+			return nil
+		})
+	}
+
+	if len(cfg.Calendars) > 0 {
+		eg.Go(func() error {
+			err := c.getCalendarMembers(slackClient, cfg.Calendars, members)
+			if err != nil {
+				c.logger.Error("failed to get the members of the calendars", zap.Error(err))
+				return err
+			}
+
+			return nil
+		})
+	}
+
+	if len(cfg.Files) > 0 {
+		eg.Go(func() error {
+			err := c.getFileMembers(slackClient, cfg.Files, members)
+			if err != nil {
+				c.logger.Error("failed to get the members of the files", zap.Error(err))
 				return err
 			}
 
@@ -164,7 +749,7 @@ func (c *Client) GetMembers(cfg *config.Members) (*slackduty.Members, error) {
 
 	if cfg.Slack != nil {
 		eg.Go(func() error {
-			err := c.getSlackUsers(cfg.Slack, members)
+			err := c.getSlackUsers(slackClient, cfg.Slack, members)
 			if err != nil {
 				c.logger.Error("failed to get Slack members", zap.Error(err))
 				return err
@@ -178,25 +763,60 @@ func (c *Client) GetMembers(cfg *config.Members) (*slackduty.Members, error) {
 		return nil, err
 	}
 
+	return c.composeMembers(slackClient, cfg, members)
+}
+
+// composeMembers applies the set operations of the members config to the
+// members resolved from the Slack and PagerDuty selectors.
+func (c *Client) composeMembers(slackClient SlackClient, cfg *config.Members, members *slackduty.Members) (*slackduty.Members, error) {
+	for _, union := range cfg.Union {
+		other, err := c.GetMembers(slackClient, union)
+		if err != nil {
+			return nil, err
+		}
+
+		members = members.Union(other)
+	}
+
+	for _, intersect := range cfg.Intersect {
+		other, err := c.GetMembers(slackClient, intersect)
+		if err != nil {
+			return nil, err
+		}
+
+		members = members.Intersect(other)
+	}
+
+	for _, subtract := range cfg.Subtract {
+		other, err := c.GetMembers(slackClient, subtract)
+		if err != nil {
+			return nil, err
+		}
+
+		members = members.Subtract(other)
+	}
+
 	return members, nil
 }
 
-func (c *Client) preCheck(group *config.Group) error {
+// preCheck checks that the usergroups of the group exist. It returns the
+// usergroups that are disabled so that they can be enabled before the update.
+func (c *Client) preCheck(slackClient SlackClient, group *config.Group) (map[string]bool, error) {
 	c.logger.Info("precheck started", zap.String("group", group.Name), zap.String("schedule", group.Schedule))
-	ugs, err := c.slack.GetUsergroups()
+	ugs, err := slackClient.GetUsergroups()
 	if err != nil {
 		c.logger.Info("precheck failed to get Slack usergroups", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	var exists bool
+	disabled := map[string]bool{}
 
-LOOP:
 	for _, ug := range ugs {
 		for _, usergroup := range group.Usergroups {
 			s := strings.Split(usergroup, ":")
 			if len(s) != 2 {
-				return fmt.Errorf("usergroups is specified in wrong format user: %s", usergroup)
+				return nil, fmt.Errorf("usergroups is specified in wrong format user: %s", usergroup)
 			}
 
 			kind := s[0]
@@ -206,24 +826,43 @@ LOOP:
 			case "handle":
 				if ug.Handle == val {
 					exists = true
-					break LOOP
+					disabled[usergroup] = ug.DateDelete != 0
+				}
+			case "id":
+				if ug.ID == val {
+					exists = true
+					disabled[usergroup] = ug.DateDelete != 0
 				}
+			default:
+				return nil, fmt.Errorf("usergroup kind %s is invalid, must be handle or id for usergroup: %s", kind, usergroup)
 			}
 		}
 	}
 
 	if !exists {
-		return errors.New("slack user group handle doesn't exists")
+		return nil, errors.New("slack user group handle doesn't exists")
 	}
 
 	c.logger.Info("precheck success", zap.String("group", group.Name), zap.String("schedule", group.Schedule))
-	return nil
+	return disabled, nil
 }
 
-func (c *Client) getPagerDutyMembers(pdConfig *config.Pagerduty, members *slackduty.Members) error {
+func (c *Client) getPagerDutyMembers(slackClient SlackClient, pdClient PagerdutyClient, pdConfig *config.Pagerduty, members *slackduty.Members) error {
+	if err := validateTeamRoles(pdConfig.TeamRoles); err != nil {
+		return err
+	}
+
+	if err := validateUserRoles(pdConfig.UserRoles); err != nil {
+		return err
+	}
+
+	if err := validateIncidents(pdConfig.Incidents); err != nil {
+		return err
+	}
+
 	eg := errgroup.Group{}
 	eg.Go(func() error {
-		err := c.getPagerdutySchedules(pdConfig.Schedules, members)
+		err := c.getPagerdutySchedules(slackClient, pdClient, pdConfig, members)
 		if err != nil {
 			c.logger.Error("failed to get PagerDuty schedules members", zap.Error(err))
 			return err
@@ -233,7 +872,17 @@ func (c *Client) getPagerDutyMembers(pdConfig *config.Pagerduty, members *slackd
 	})
 
 	eg.Go(func() error {
-		err := c.getPagerdutyServices(pdConfig.Services, members)
+		err := c.getPagerdutyIncidents(slackClient, pdClient, pdConfig, members)
+		if err != nil {
+			c.logger.Error("failed to get PagerDuty incidents members", zap.Error(err))
+			return err
+		}
+
+		return nil
+	})
+
+	eg.Go(func() error {
+		err := c.getPagerdutyServices(slackClient, pdClient, pdConfig, members)
 		if err != nil {
 			c.logger.Error("failed to get PagerDuty services members", zap.Error(err))
 			return err
@@ -243,7 +892,7 @@ func (c *Client) getPagerDutyMembers(pdConfig *config.Pagerduty, members *slackd
 	})
 
 	eg.Go(func() error {
-		err := c.getPagerdutyTeams(pdConfig.Teams, members)
+		err := c.getPagerdutyTeams(slackClient, pdClient, pdConfig, members)
 		if err != nil {
 			c.logger.Error("failed to get PagerDuty teams members", zap.Error(err))
 			return err
@@ -253,7 +902,7 @@ func (c *Client) getPagerDutyMembers(pdConfig *config.Pagerduty, members *slackd
 	})
 
 	eg.Go(func() error {
-		err := c.getPagerdutyUsers(pdConfig.Users, members)
+		err := c.getPagerdutyUsers(slackClient, pdClient, pdConfig, members)
 		c.logger.Error("failed to get PagerDuty users members", zap.Error(err))
 		if err != nil {
 			return err
@@ -269,24 +918,29 @@ func (c *Client) getPagerDutyMembers(pdConfig *config.Pagerduty, members *slackd
 	return nil
 }
 
-func (c *Client) getPagerdutySchedules(schedules []string, members *slackduty.Members) error {
+func (c *Client) getPagerdutySchedules(slackClient SlackClient, pdClient PagerdutyClient, pdConfig *config.Pagerduty, members *slackduty.Members) error {
 	eg := errgroup.Group{}
-	for _, schedule := range schedules {
+	for _, schedule := range pdConfig.Schedules {
 		schedule := schedule
 		eg.Go(func() error {
-			pdUsers, err := c.pagerduty.GetScheduledUser(schedule)
+			pdUsers, ends, err := c.getScheduledUsers(pdClient, schedule)
 			if err != nil {
 				return err
 			}
 
 			for _, pdUser := range pdUsers {
-				slackUser, err := c.slack.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
+				if !acceptUser(pdConfig, &pdUser) {
+					continue
+				}
+
+				slackUser, err := slackClient.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
 				if err != nil {
 					return err
 				}
 
 				member := convSlackUser(slackUser, pdUser.Email)
-				members.Add(member)
+				member.Until = ends[pdUser.Email]
+				members.AddFrom(pagerdutySource(pdConfig.Account, "schedule", scheduleSelector(schedule)), member)
 			}
 
 			return nil
@@ -300,24 +954,28 @@ func (c *Client) getPagerdutySchedules(schedules []string, members *slackduty.Me
 	return nil
 }
 
-func (c *Client) getPagerdutyServices(svcs []string, members *slackduty.Members) error {
+func (c *Client) getPagerdutyServices(slackClient SlackClient, pdClient PagerdutyClient, pdConfig *config.Pagerduty, members *slackduty.Members) error {
 	eg := errgroup.Group{}
-	for _, svc := range svcs {
+	for _, svc := range pdConfig.Services {
 		svc := svc
 		eg.Go(func() error {
-			pdUsers, err := c.pagerduty.GetService(svc)
+			pdUsers, err := pdClient.GetService(svc, pdConfig.TeamRoles)
 			if err != nil {
 				return err
 			}
 
 			for _, pdUser := range pdUsers {
-				slackUser, err := c.slack.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
+				if !acceptUser(pdConfig, &pdUser) {
+					continue
+				}
+
+				slackUser, err := slackClient.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
 				if err != nil {
 					return err
 				}
 
 				member := convSlackUser(slackUser, pdUser.Email)
-				members.Add(member)
+				members.AddFrom(pagerdutySource(pdConfig.Account, "service", svc), member)
 			}
 
 			return nil
@@ -331,24 +989,28 @@ func (c *Client) getPagerdutyServices(svcs []string, members *slackduty.Members)
 	return nil
 }
 
-func (c *Client) getPagerdutyTeams(teams []string, members *slackduty.Members) error {
+func (c *Client) getPagerdutyTeams(slackClient SlackClient, pdClient PagerdutyClient, pdConfig *config.Pagerduty, members *slackduty.Members) error {
 	eg := errgroup.Group{}
-	for _, team := range teams {
+	for _, team := range pdConfig.Teams {
 		team := team
 		eg.Go(func() error {
-			pdUsers, err := c.pagerduty.GetTeam(team)
+			pdUsers, err := pdClient.GetTeam(team, pdConfig.TeamRoles)
 			if err != nil {
 				return err
 			}
 
 			for _, pdUser := range pdUsers {
-				slackUser, err := c.slack.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
+				if !acceptUser(pdConfig, &pdUser) {
+					continue
+				}
+
+				slackUser, err := slackClient.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
 				if err != nil {
 					return err
 				}
 
 				member := convSlackUser(slackUser, pdUser.Email)
-				members.Add(member)
+				members.AddFrom(pagerdutySource(pdConfig.Account, "team", team), member)
 			}
 
 			return nil
@@ -362,23 +1024,27 @@ func (c *Client) getPagerdutyTeams(teams []string, members *slackduty.Members) e
 	return nil
 }
 
-func (c *Client) getPagerdutyUsers(users []string, members *slackduty.Members) error {
+func (c *Client) getPagerdutyUsers(slackClient SlackClient, pdClient PagerdutyClient, pdConfig *config.Pagerduty, members *slackduty.Members) error {
 	eg := errgroup.Group{}
-	for _, user := range users {
+	for _, user := range pdConfig.Users {
 		user := user
 		eg.Go(func() error {
-			pdUser, err := c.pagerduty.GetUser(user)
+			pdUser, err := pdClient.GetUser(user)
 			if err != nil {
 				return err
 			}
 
-			slackUser, err := c.slack.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
+			if !acceptUser(pdConfig, pdUser) {
+				return nil
+			}
+
+			slackUser, err := slackClient.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
 			if err != nil {
 				return err
 			}
 
 			member := convSlackUser(slackUser, pdUser.Email)
-			members.Add(member)
+			members.AddFrom(pagerdutySource(pdConfig.Account, "user", user), member)
 			return nil
 		})
 	}
@@ -390,12 +1056,35 @@ func (c *Client) getPagerdutyUsers(users []string, members *slackduty.Members) e
 	return nil
 }
 
-func (c *Client) getSlackUsers(users *config.Slack, members *slackduty.Members) error {
+func (c *Client) getSlackUsers(slackClient SlackClient, users *config.Slack, members *slackduty.Members) error {
 	eg := errgroup.Group{}
 	for _, user := range *users {
 		user := user
 		eg.Go(func() error {
-			slackUser, err := c.slack.GetUser(user)
+			s := strings.Split(user, ":")
+			switch s[0] {
+			case "usergroup", "channel":
+				var ids []string
+				var err error
+				switch {
+				case s[0] == "usergroup" && len(s) == 2:
+					ids, err = slackClient.GetUsergroupMembers(fmt.Sprintf("handle:%s", s[1]))
+				case s[0] == "usergroup" && len(s) == 3 && (s[1] == "id" || s[1] == "handle"):
+					ids, err = slackClient.GetUsergroupMembers(fmt.Sprintf("%s:%s", s[1], s[2]))
+				case s[0] == "channel" && len(s) == 2:
+					ids, err = slackClient.GetChannelMembers(s[1])
+				default:
+					return fmt.Errorf("slack member is specified in wrong format member: %s", user)
+				}
+
+				if err != nil {
+					return err
+				}
+
+				return c.addSlackIDs(slackClient, slackSource(user), ids, members)
+			}
+
+			slackUser, err := slackClient.GetUser(user)
 			if err != nil {
 				return err
 			}
@@ -406,7 +1095,7 @@ func (c *Client) getSlackUsers(users *config.Slack, members *slackduty.Members)
 			}
 
 			member := convSlackUser(slackUser, email)
-			members.Add(member)
+			members.AddFrom(slackSource(user), member)
 
 			return nil
 		})
@@ -419,8 +1108,34 @@ func (c *Client) getSlackUsers(users *config.Slack, members *slackduty.Members)
 	return nil
 }
 
-func (c *Client) updateUsergroup(handle string, members []slackduty.Member) error {
+// addSlackIDs adds the members of the channel or the usergroup. The profile
+// of each member is fetched so that the excludes by the email match them. The
+// bots and the deactivated users are skipped.
+func (c *Client) addSlackIDs(slackClient SlackClient, source string, ids []string, members *slackduty.Members) error {
+	eg := errgroup.Group{}
+	for _, id := range ids {
+		id := id
+		eg.Go(func() error {
+			slackUser, err := slackClient.GetUserInfo(id)
+			if err != nil {
+				return fmt.Errorf("failed to get the Slack user id: %s error: %v", id, err)
+			}
+
+			if slackUser.IsBot || slackUser.Deleted {
+				c.logger.Debug("skipped a bot or deactivated user", zap.String("source", source), zap.String("id", id))
+				return nil
+			}
+
+			members.AddFrom(source, convSlackUser(slackUser, slackUser.Profile.Email))
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
+
+func (c *Client) updateUsergroup(slackClient SlackClient, handle string, members []slackduty.Member) error {
 	flatMembers := slackduty.FlattenMembers(members)
-	err := c.slack.UpdateUsergroup(handle, flatMembers)
+	err := slackClient.UpdateUsergroup(handle, flatMembers)
 	return err
 }
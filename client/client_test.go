@@ -1,10 +1,20 @@
 package client
 
 import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/KeisukeYamashita/slackduty/config"
 	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"github.com/KeisukeYamashita/slackduty/state"
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/slack-go/slack"
 )
 
 func TestWithExtenralTrigger(t *testing.T) {
@@ -27,6 +37,46 @@ func TestWithExtenralTrigger(t *testing.T) {
 	}
 }
 
+func TestWithWorkspace(t *testing.T) {
+	var o options
+	WithWorkspace("eu", "test-slack-key", "T0123")(&o)
+	WithWorkspace("us", "test-slack-key", "")(&o)
+
+	if len(o.workspaces) != 2 {
+		t.Fatalf("workspace count doesn't match got: %d want: 2", len(o.workspaces))
+	}
+
+	if o.workspaces[0].name != "eu" || o.workspaces[0].teamID != "T0123" {
+		t.Fatalf("workspace is unexpected got: %+v", o.workspaces[0])
+	}
+}
+
+func TestSlackClient(t *testing.T) {
+	c, err := New(&config.Config{}, "test-pd-key", "test-slack-key", log.NewDiscard(), WithWorkspace("eu", "test-slack-key", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := map[string]struct {
+		workspace string
+		success   bool
+	}{
+		"empty workspace":   {"", true},
+		"default workspace": {defaultWorkspace, true},
+		"named workspace":   {"eu", true},
+		"unknown workspace": {"us", false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			_, err := c.slackClient(tc.workspace)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+		})
+	}
+}
+
 func TestNew(t *testing.T) {
 	const (
 		testPdAPIKey    = "test-pd-key"
@@ -41,17 +91,859 @@ func TestNew(t *testing.T) {
 		slackAPIKey string
 	}
 
+	invalidAlert := &config.Config{Alert: &config.Alert{Slack: &config.SlackAlert{Channel: "#ops", Workspace: "unknown"}}}
+
 	tcs := map[string]struct {
-		input *input
-		want  *Client
+		input   *input
+		want    *Client
+		success bool
 	}{
-		"api keys configured": {&input{&config.Config{}, testPdAPIKey, testSlackAPIKey}, &Client{pagerduty: NewPagerDutyClient(testPdAPIKey), slack: NewSlackClient(testSlackAPIKey), logger: testLogger}},
+		"api keys configured": {&input{&config.Config{}, testPdAPIKey, testSlackAPIKey}, &Client{pagerduty: NewPagerDutyClient(testPdAPIKey), slack: NewSlackClient(testSlackAPIKey), logger: testLogger}, true},
+		"invalid alert":       {&input{invalidAlert, testPdAPIKey, testSlackAPIKey}, nil, false},
 	}
 
 	for n, tc := range tcs {
 		t.Run(n, func(t *testing.T) {
-			got := New(tc.input.config, tc.input.pdAPIKey, tc.input.slackAPIKey, testLogger)
+			got, err := New(tc.input.config, tc.input.pdAPIKey, tc.input.slackAPIKey, testLogger)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
 			_ = got
 		})
 	}
 }
+
+type fakeSlackClient struct {
+	mux        sync.Mutex
+	users      map[string]*slack.User
+	usergroups map[string][]string
+	channels   map[string][]string
+	disabled   map[string]bool
+	messages   map[string][]string
+}
+
+var _ SlackClient = (*fakeSlackClient)(nil)
+
+func newFakeSlackClient() *fakeSlackClient {
+	return &fakeSlackClient{
+		users:      map[string]*slack.User{},
+		usergroups: map[string][]string{},
+		channels:   map[string][]string{},
+		disabled:   map[string]bool{},
+		messages:   map[string][]string{},
+	}
+}
+
+func (c *fakeSlackClient) CreateUsergroup() error {
+	return nil
+}
+
+func (c *fakeSlackClient) DisableUsergroup(handle string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.disabled[handle] = true
+	return nil
+}
+
+func (c *fakeSlackClient) EnableUsergroup(handle string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.disabled[handle] = false
+	return nil
+}
+
+func (c *fakeSlackClient) GetUser(user string) (*slack.User, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if u, ok := c.users[user]; ok {
+		return u, nil
+	}
+
+	if s := strings.Split(user, ":"); s[0] == "id" {
+		return &slack.User{ID: s[1]}, nil
+	}
+
+	return nil, fmt.Errorf("user not found user: %s", user)
+}
+
+func (c *fakeSlackClient) GetChannelMembers(channel string) ([]string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if members, ok := c.channels[channel]; ok {
+		return members, nil
+	}
+
+	return nil, fmt.Errorf("channel not found channel: %s", channel)
+}
+
+func (c *fakeSlackClient) GetUserInfo(id string) (*slack.User, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if u, ok := c.users["id:"+id]; ok {
+		return u, nil
+	}
+
+	return &slack.User{ID: id, TeamID: "T1"}, nil
+}
+
+func (c *fakeSlackClient) TeamID() (string, error) {
+	return "T1", nil
+}
+
+func (c *fakeSlackClient) GetUsergroupMembers(handle string) ([]string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.usergroups[handle], nil
+}
+
+func (c *fakeSlackClient) GetUsergroups() ([]slack.UserGroup, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	ugs := []slack.UserGroup{}
+	for handle, users := range c.usergroups {
+		ug := slack.UserGroup{ID: handle, Handle: strings.TrimPrefix(handle, "handle:"), Users: users}
+		if strings.HasPrefix(handle, "id:") {
+			ug.ID, ug.Handle = strings.TrimPrefix(handle, "id:"), ""
+		}
+		if c.disabled[handle] {
+			ug.DateDelete = 1
+		}
+		ugs = append(ugs, ug)
+	}
+
+	return ugs, nil
+}
+
+func (c *fakeSlackClient) PostMessage(channel, text string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.messages[channel] = append(c.messages[channel], text)
+	return nil
+}
+
+func (c *fakeSlackClient) UpdateUsergroup(handle string, members string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.usergroups[handle] = strings.Split(members, ",")
+	return nil
+}
+
+func TestConfigureGroup_Workspaces(t *testing.T) {
+	failing := newFakeSlackClient()
+	us := newFakeSlackClient()
+	us.usergroups["handle:oncall"] = []string{"U2"}
+	eu := newFakeSlackClient()
+	eu.usergroups["handle:oncall"] = []string{"U2"}
+
+	c := &Client{slack: failing, workspaces: map[string]SlackClient{"us": us, "eu": eu}, logger: log.NewDiscard()}
+	group := &config.Group{
+		Name:       "test",
+		Workspaces: []string{"default", "us", "unknown", "eu"},
+		Usergroups: []string{"handle:oncall"},
+		Members:    &config.Members{Slack: &config.Slack{"id:U1"}},
+	}
+
+	err := c.configureGroup(group)
+	if err == nil {
+		t.Fatal("the failed workspaces should be returned")
+	}
+
+	for _, workspace := range []string{"default", "unknown"} {
+		if !strings.Contains(err.Error(), "workspace: "+workspace) {
+			t.Fatalf("error should have the workspace %s got: %v", workspace, err)
+		}
+	}
+
+	for name, slackClient := range map[string]*fakeSlackClient{"us": us, "eu": eu} {
+		if got := slackClient.usergroups["handle:oncall"]; !reflect.DeepEqual(got, []string{"U1"}) {
+			t.Fatalf("usergroup of the workspace %s doesn't match got: %v want: [U1]", name, got)
+		}
+	}
+}
+
+func TestConfigureGroup_DisabledUsergroupID(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["id:S1"] = []string{"U2"}
+	slackClient.disabled["id:S1"] = true
+
+	c := &Client{slack: slackClient, logger: log.NewDiscard()}
+	group := &config.Group{
+		Name:       "test",
+		Usergroups: []string{"id:S1"},
+		Members:    &config.Members{Slack: &config.Slack{"id:U1"}},
+	}
+
+	if err := c.configureGroup(group); err != nil {
+		t.Fatal(err)
+	}
+
+	if slackClient.disabled["id:S1"] {
+		t.Fatal("the disabled usergroup should be enabled")
+	}
+
+	if got := slackClient.usergroups["id:S1"]; !reflect.DeepEqual(got, []string{"U1"}) {
+		t.Fatalf("usergroup doesn't match got: %v want: [U1]", got)
+	}
+}
+
+func TestConfigureGroup_Notify(t *testing.T) {
+	tcs := map[string]struct {
+		notify   *config.Notify
+		current  []string
+		messaged []string
+	}{
+		"notification disabled": {nil, []string{"U1"}, []string{}},
+		"notify added":          {&config.Notify{Added: true}, []string{"U1"}, []string{"U2"}},
+		"notify removed":        {&config.Notify{Removed: true}, []string{"U1"}, []string{"U1"}},
+		"notify both":           {&config.Notify{Added: true, Removed: true}, []string{"U1"}, []string{"U1", "U2"}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			slackClient.usergroups["handle:oncall"] = tc.current
+			c := &Client{slack: slackClient, logger: log.NewDiscard()}
+
+			group := &config.Group{
+				Name:       "test",
+				Usergroups: []string{"handle:oncall"},
+				Members:    &config.Members{Slack: &config.Slack{"id:U2"}},
+				Notify:     tc.notify,
+			}
+
+			if err := c.configureGroup(group); err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			got := []string{}
+			for id := range slackClient.messages {
+				got = append(got, id)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.messaged) {
+				t.Fatalf("messaged users doesn't match got: %v want: %v", got, tc.messaged)
+			}
+		})
+	}
+}
+
+func TestConfigureGroup_NotifyShiftEnd(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tcs := map[string]struct {
+		schedule config.Schedule
+		want     map[string][]string
+	}{
+		"all users": {config.Schedule{Schedule: "name:web"}, map[string][]string{
+			"U1": {"You're now in @oncall (test) until Tuesday 09:00."},
+			"U2": {"You're now in @oncall (test)."},
+		}},
+		"at +0h": {config.Schedule{Schedule: "name:web", At: "+0h"}, map[string][]string{
+			"U1": {"You're now in @oncall (test) until Tuesday 09:00."},
+		}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+			slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+			slackClient.usergroups["handle:oncall"] = []string{}
+
+			pdClient := newFakePagerdutyClient()
+			pdClient.users["id:P1"] = &pagerduty.User{APIObject: pagerduty.APIObject{ID: "P1"}, Email: "alice@example.com"}
+			pdClient.schedules["name:web"] = []pagerduty.User{*pdClient.users["id:P1"], {APIObject: pagerduty.APIObject{ID: "P2"}, Email: "bob@example.com"}}
+			pdClient.rendered["name:web"] = &pagerduty.Schedule{
+				FinalSchedule: pagerduty.ScheduleLayer{
+					RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{
+						scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-02T09:00:00Z"),
+						scheduleEntry("P2", "2020-06-02T09:00:00Z", "2020-06-03T09:00:00Z"),
+					},
+				},
+			}
+
+			c := &Client{pagerduty: pdClient, slack: slackClient, now: func() time.Time { return now }, logger: log.NewDiscard()}
+			group := &config.Group{
+				Name:       "test",
+				Usergroups: []string{"handle:oncall"},
+				Members:    &config.Members{Pagerduty: config.Pagerduties{{Schedules: []config.Schedule{tc.schedule}}}},
+				Notify:     &config.Notify{Added: true},
+			}
+
+			if err := c.configureGroup(group); err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			if !reflect.DeepEqual(slackClient.messages, tc.want) {
+				t.Fatalf("messages doesn't match got: %v want: %v", slackClient.messages, tc.want)
+			}
+		})
+	}
+}
+
+func TestShiftEnd(t *testing.T) {
+	now := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	tcs := map[string]struct {
+		until time.Time
+		want  string
+	}{
+		"unknown":           {time.Time{}, ""},
+		"within a week":     {time.Date(2020, 1, 3, 10, 0, 0, 0, time.UTC), " until Friday 10:00"},
+		"later than a week": {time.Date(2020, 1, 10, 10, 0, 0, 0, time.UTC), " until Friday, Jan 10 10:00"},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if got := shiftEnd(tc.until, now); got != tc.want {
+				t.Fatalf("shift end doesn't match got: %q want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+type fakePagerdutyClient struct {
+	incidents []Incident
+	rendered  map[string]*pagerduty.Schedule
+	schedules map[string][]pagerduty.User
+	services  map[string][]pagerduty.User
+	teams     map[string][]pagerduty.User
+	users     map[string]*pagerduty.User
+}
+
+var _ PagerdutyClient = (*fakePagerdutyClient)(nil)
+
+func newFakePagerdutyClient() *fakePagerdutyClient {
+	return &fakePagerdutyClient{
+		rendered:  map[string]*pagerduty.Schedule{},
+		schedules: map[string][]pagerduty.User{},
+		services:  map[string][]pagerduty.User{},
+		teams:     map[string][]pagerduty.User{},
+		users:     map[string]*pagerduty.User{},
+	}
+}
+
+func (c *fakePagerdutyClient) GetScheduledUser(schedule string) ([]pagerduty.User, error) {
+	return c.schedules[schedule], nil
+}
+
+func (c *fakePagerdutyClient) ListIncidents(services []string, urgencies []string) ([]Incident, error) {
+	incidents := []Incident{}
+	for _, incident := range c.incidents {
+		if len(services) > 0 && !hasRole(services, "id:"+incident.Service.ID) {
+			continue
+		}
+
+		if !hasRole(urgencies, incident.Urgency) {
+			continue
+		}
+
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, nil
+}
+
+func (c *fakePagerdutyClient) RenderSchedule(schedule string, since, until time.Time) (*pagerduty.Schedule, error) {
+	if s, ok := c.rendered[schedule]; ok {
+		return s, nil
+	}
+
+	if _, ok := c.schedules[schedule]; ok {
+		return &pagerduty.Schedule{}, nil
+	}
+
+	return nil, fmt.Errorf("no schedule exists for schedule: %s", schedule)
+}
+
+func (c *fakePagerdutyClient) GetService(service string, roles []string) ([]pagerduty.User, error) {
+	return filterRole(c.services[service], roles), nil
+}
+
+func (c *fakePagerdutyClient) GetTeam(team string, roles []string) ([]pagerduty.User, error) {
+	return filterRole(c.teams[team], roles), nil
+}
+
+// filterRole filters the users by the role. The fake uses the role of the
+// user as the role in the team.
+func filterRole(users []pagerduty.User, roles []string) []pagerduty.User {
+	result := []pagerduty.User{}
+	for _, user := range users {
+		if hasRole(roles, user.Role) {
+			result = append(result, user)
+		}
+	}
+
+	return result
+}
+
+func (c *fakePagerdutyClient) GetUser(user string) (*pagerduty.User, error) {
+	if u, ok := c.users[user]; ok {
+		return u, nil
+	}
+
+	return nil, fmt.Errorf("no user exists user: %s", user)
+}
+
+func TestGetMembers_Accounts(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+
+	pdClient := newFakePagerdutyClient()
+	pdClient.teams["name:web"] = []pagerduty.User{{Email: "alice@example.com"}}
+
+	legacyClient := newFakePagerdutyClient()
+	legacyClient.teams["name:web"] = []pagerduty.User{{Email: "alice@example.com"}, {Email: "bob@example.com"}}
+
+	c := &Client{
+		pagerduty: pdClient,
+		accounts:  map[string]PagerdutyClient{"legacy": legacyClient},
+		slack:     slackClient,
+		logger:    log.NewDiscard(),
+	}
+
+	tcs := map[string]struct {
+		cfg     *config.Members
+		want    []string
+		success bool
+	}{
+		"default account": {&config.Members{Pagerduty: config.Pagerduties{{Teams: []string{"name:web"}}}}, []string{"U1"}, true},
+		"named account":   {&config.Members{Pagerduty: config.Pagerduties{{Account: "legacy", Teams: []string{"name:web"}}}}, []string{"U1", "U2"}, true},
+		"merged accounts": {&config.Members{Pagerduty: config.Pagerduties{{Teams: []string{"name:web"}}, {Account: "legacy", Teams: []string{"name:web"}}}}, []string{"U1", "U2"}, true},
+		"unknown account": {&config.Members{Pagerduty: config.Pagerduties{{Account: "unknown", Teams: []string{"name:web"}}}}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			members, err := c.GetMembers(slackClient, tc.cfg)
+			if err != nil {
+				if tc.success {
+					t.Fatalf("test %s error: %v", n, err)
+				} else {
+					return
+				}
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+type fakeStore struct {
+	mux       sync.Mutex
+	records   []state.Record
+	paused    map[string]state.Pause
+	overrides []state.Override
+}
+
+var _ state.Store = (*fakeStore)(nil)
+
+func (s *fakeStore) Save(record state.Record) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *fakeStore) History(key string) ([]state.Record, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	records := []state.Record{}
+	for _, record := range s.records {
+		if state.Match(record, key) {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+func (s *fakeStore) Pause(pause state.Pause) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.paused == nil {
+		s.paused = map[string]state.Pause{}
+	}
+	s.paused[pause.Group] = pause
+	return nil
+}
+
+func (s *fakeStore) Resume(group string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	delete(s.paused, group)
+	return nil
+}
+
+func (s *fakeStore) Paused(group string) (*state.Pause, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	pause, ok := s.paused[group]
+	if !ok {
+		return nil, nil
+	}
+	return &pause, nil
+}
+
+func (s *fakeStore) AddOverride(override state.Override, now time.Time) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.overrides = append(s.overrides, override)
+	return nil
+}
+
+func (s *fakeStore) DeleteOverride(id string) error {
+	return nil
+}
+
+func (s *fakeStore) Overrides(group string) ([]state.Override, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	overrides := []state.Override{}
+	for _, override := range s.overrides {
+		if override.Group == group {
+			overrides = append(overrides, override)
+		}
+	}
+	return overrides, nil
+}
+
+func (s *fakeStore) Close() error {
+	return nil
+}
+
+func TestGetMembers_SetOperations(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+	slackClient.users["email:carol@example.com"] = &slack.User{ID: "U3"}
+
+	pdClient := newFakePagerdutyClient()
+	pdClient.teams["name:web"] = []pagerduty.User{{Email: "alice@example.com"}, {Email: "bob@example.com"}}
+	pdClient.schedules["name:web-oncall"] = []pagerduty.User{{Email: "bob@example.com"}, {Email: "carol@example.com"}}
+
+	c := &Client{pagerduty: pdClient, slack: slackClient, logger: log.NewDiscard()}
+
+	team := config.Pagerduties{{Teams: []string{"name:web"}}}
+	oncall := &config.Members{Pagerduty: config.Pagerduties{{Schedules: []config.Schedule{{Schedule: "name:web-oncall"}}}}}
+
+	tcs := map[string]struct {
+		cfg  *config.Members
+		want []string
+	}{
+		"union":             {&config.Members{Pagerduty: team, Union: []*config.Members{oncall}}, []string{"U1", "U2", "U3"}},
+		"intersect":         {&config.Members{Pagerduty: team, Intersect: []*config.Members{oncall}}, []string{"U2"}},
+		"subtract":          {&config.Members{Pagerduty: team, Subtract: []*config.Members{oncall}}, []string{"U1"}},
+		"subtract a user":   {&config.Members{Pagerduty: team, Subtract: []*config.Members{{Slack: &config.Slack{"id:U1"}}}}, []string{"U2"}},
+		"nested operations": {&config.Members{Union: []*config.Members{{Pagerduty: team, Intersect: []*config.Members{oncall}}, {Slack: &config.Slack{"id:U4"}}}}, []string{"U2", "U4"}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			members, err := c.GetMembers(slackClient, tc.cfg)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetMembers_SlackSources(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:web-oncall"] = []string{"U1", "U2"}
+	slackClient.usergroups["handle:db-oncall"] = []string{"U2", "U3"}
+	slackClient.usergroups["id:S0123"] = []string{"U9"}
+	slackClient.channels["#web-team"] = []string{"U4"}
+	slackClient.channels["#ops"] = []string{"U6", "U7", "U8"}
+	slackClient.users["id:U6"] = &slack.User{ID: "U6", IsBot: true}
+	slackClient.users["id:U7"] = &slack.User{ID: "U7", Deleted: true}
+	slackClient.users["id:U8"] = &slack.User{ID: "U8", Profile: slack.UserProfile{Email: "alice@example.com"}}
+
+	c := &Client{slack: slackClient, logger: log.NewDiscard()}
+
+	tcs := map[string]struct {
+		cfg     *config.Members
+		want    []string
+		success bool
+	}{
+		"usergroups":                 {&config.Members{Slack: &config.Slack{"usergroup:web-oncall", "usergroup:db-oncall"}}, []string{"U1", "U2", "U3"}, true},
+		"usergroup by id and handle": {&config.Members{Slack: &config.Slack{"usergroup:id:S0123", "usergroup:handle:web-oncall"}}, []string{"U1", "U2", "U9"}, true},
+		"channel":                    {&config.Members{Slack: &config.Slack{"channel:#web-team", "id:U5"}}, []string{"U4", "U5"}, true},
+		"bots and deactivated users": {&config.Members{Slack: &config.Slack{"channel:#ops"}}, []string{"U8"}, true},
+		"unknown channel":            {&config.Members{Slack: &config.Slack{"channel:#unknown"}}, nil, false},
+		"wrong format":               {&config.Members{Slack: &config.Slack{"usergroup"}}, nil, false},
+		"wrong usergroup kind":       {&config.Members{Slack: &config.Slack{"usergroup:name:web-oncall"}}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			members, err := c.GetMembers(slackClient, tc.cfg)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if !tc.success {
+				return
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetMembers_SlackSourcesExclude(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:web-oncall"] = []string{"U1", "U2"}
+	slackClient.users["id:U1"] = &slack.User{ID: "U1", Profile: slack.UserProfile{Email: "alice@example.com"}}
+
+	c := &Client{slack: slackClient, logger: log.NewDiscard()}
+	group := &config.Group{Name: "test", Exclude: []string{"email:alice@example.com"}}
+
+	members, err := c.GetMembers(slackClient, &config.Members{Slack: &config.Slack{"usergroup:web-oncall"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	members, err = c.refineMembers(slackClient, group, defaultWorkspace, members)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(members.Members) != 1 || members.Members[0].ID != "U2" {
+		t.Fatalf("members don't match got: %v want: [U2]", members.Members)
+	}
+}
+
+func TestGetMembers_TeamRoles(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+	slackClient.users["email:carol@example.com"] = &slack.User{ID: "U3"}
+
+	pdClient := newFakePagerdutyClient()
+	pdClient.teams["name:web"] = []pagerduty.User{
+		{Email: "alice@example.com", Role: "manager"},
+		{Email: "bob@example.com", Role: "responder"},
+		{Email: "carol@example.com", Role: "observer"},
+	}
+
+	c := &Client{pagerduty: pdClient, slack: slackClient, logger: log.NewDiscard()}
+
+	tcs := map[string]struct {
+		roles   []string
+		want    []string
+		success bool
+	}{
+		"all roles":    {nil, []string{"U1", "U2", "U3"}, true},
+		"managers":     {[]string{"manager"}, []string{"U1"}, true},
+		"responders":   {[]string{"manager", "responder"}, []string{"U1", "U2"}, true},
+		"invalid role": {[]string{"owner"}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			cfg := &config.Members{Pagerduty: config.Pagerduties{{Teams: []string{"name:web"}, TeamRoles: tc.roles}}}
+			members, err := c.GetMembers(slackClient, cfg)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if !tc.success {
+				return
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfigureGroup_Record(t *testing.T) {
+	tcs := map[string]struct {
+		members *config.Members
+		want    state.Outcome
+	}{
+		"success": {&config.Members{Slack: &config.Slack{"id:U1"}}, state.OutcomeSuccess},
+		"skipped": {&config.Members{}, state.OutcomeSkipped},
+		"failed":  {&config.Members{Slack: &config.Slack{"email:unknown@example.com"}}, state.OutcomeFailed},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			slackClient.usergroups["handle:oncall"] = []string{}
+			store := &fakeStore{}
+			c := &Client{slack: slackClient, store: store, logger: log.NewDiscard()}
+
+			group := &config.Group{
+				Name:       "test",
+				Usergroups: []string{"handle:oncall"},
+				Members:    tc.members,
+			}
+			c.configureGroup(group)
+
+			if len(store.records) != 1 {
+				t.Fatalf("record count doesn't match got: %d want: 1", len(store.records))
+			}
+
+			if got := store.records[0].Outcome; got != tc.want {
+				t.Fatalf("outcome doesn't match got: %s want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:oncall"] = []string{"U2"}
+	store := &fakeStore{}
+	group := config.Group{
+		Name:       "test",
+		Usergroups: []string{"handle:oncall"},
+		Members:    &config.Members{Slack: &config.Slack{"id:U2"}},
+	}
+	c := &Client{
+		config: &config.Config{Groups: []config.Group{group}},
+		slack:  slackClient,
+		store:  store,
+		logger: log.NewDiscard(),
+	}
+
+	records := []state.Record{
+		{RunID: "run1", Group: "test", Workspace: defaultWorkspace, Usergroup: "handle:oncall", Outcome: state.OutcomeSuccess, Members: []slackduty.Member{{ID: "U1"}}},
+	}
+
+	if _, err := c.Rollback("handle:oncall", records); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := slackClient.usergroups["handle:oncall"]; len(got) != 1 || got[0] != "U1" {
+		t.Fatalf("usergroup is not rolled back got: %v want: [U1]", got)
+	}
+
+	if pause, _ := store.Paused("test"); pause == nil {
+		t.Fatal("group should be paused")
+	}
+
+	if err := c.configureGroup(&c.config.Groups[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := slackClient.usergroups["handle:oncall"]; len(got) != 1 || got[0] != "U1" {
+		t.Fatalf("paused group should not be synchronized got: %v want: [U1]", got)
+	}
+
+	if err := c.Resume("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.configureGroup(&c.config.Groups[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := slackClient.usergroups["handle:oncall"]; len(got) != 1 || got[0] != "U2" {
+		t.Fatalf("resumed group should be synchronized got: %v want: [U2]", got)
+	}
+}
+
+func TestRollback_Failed(t *testing.T) {
+	store := &fakeStore{}
+	group := config.Group{
+		Name:       "test",
+		Usergroups: []string{"handle:oncall"},
+		Members:    &config.Members{Slack: &config.Slack{"id:U2"}},
+	}
+	c := &Client{
+		config: &config.Config{Groups: []config.Group{group}},
+		slack:  newFakeSlackClient(),
+		store:  store,
+		logger: log.NewDiscard(),
+	}
+
+	records := []state.Record{
+		{RunID: "run1", Group: "test", Workspace: "unknown", Usergroup: "handle:oncall", Outcome: state.OutcomeSuccess, Members: []slackduty.Member{{ID: "U1"}}},
+	}
+
+	if _, err := c.Rollback("handle:oncall", records); err == nil {
+		t.Fatal("rollback should fail for the unknown workspace")
+	}
+
+	if pause, _ := store.Paused("test"); pause == nil {
+		t.Fatal("group should be kept paused")
+	}
+}
+
+func TestConfigureGroup_Overrides(t *testing.T) {
+	now := time.Now()
+	tcs := map[string]struct {
+		overrides []state.Override
+		want      []string
+	}{
+		"no override":      {nil, []string{"U1", "U2"}},
+		"add":              {[]state.Override{{ID: "o1", Group: "test", Action: state.ActionAdd, User: "id:U3", Until: now.Add(time.Hour)}}, []string{"U1", "U2", "U3"}},
+		"remove":           {[]state.Override{{ID: "o1", Group: "test", Action: state.ActionRemove, User: "id:U1", Until: now.Add(time.Hour)}}, []string{"U2"}},
+		"expired":          {[]state.Override{{ID: "o1", Group: "test", Action: state.ActionAdd, User: "id:U3", Until: now.Add(-time.Hour)}}, []string{"U1", "U2"}},
+		"other group":      {[]state.Override{{ID: "o1", Group: "other", Action: state.ActionAdd, User: "id:U3", Until: now.Add(time.Hour)}}, []string{"U1", "U2"}},
+		"add excluded one": {[]state.Override{{ID: "o1", Group: "test", Action: state.ActionAdd, User: "id:U4", Until: now.Add(time.Hour)}}, []string{"U1", "U2", "U4"}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			slackClient.usergroups["handle:oncall"] = []string{}
+			store := &fakeStore{overrides: tc.overrides}
+			c := &Client{slack: slackClient, store: store, logger: log.NewDiscard()}
+
+			group := &config.Group{
+				Name:       "test",
+				Exclude:    []string{"id:U4"},
+				Usergroups: []string{"handle:oncall"},
+				Members:    &config.Members{Slack: &config.Slack{"id:U1", "id:U2", "id:U4"}},
+			}
+
+			if err := c.configureGroup(group); err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			got := slackClient.usergroups["handle:oncall"]
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members don't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
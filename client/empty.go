@@ -0,0 +1,42 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+)
+
+const (
+	onEmptyKeep     = "keep"
+	onEmptyClear    = "clear"
+	onEmptyFallback = "fallback"
+)
+
+// onEmpty returns the policy of the group when no member is resolved. The
+// default is fallback if the fallback members are configured, clear if the
+// group is configured to be disabled when empty, otherwise keep.
+func onEmpty(group *config.Group) (string, error) {
+	switch group.OnEmpty {
+	case "":
+		if group.Fallback != nil {
+			return onEmptyFallback, nil
+		}
+
+		if group.Disable != nil && group.Disable.Empty {
+			return onEmptyClear, nil
+		}
+
+		return onEmptyKeep, nil
+	case onEmptyKeep, onEmptyClear:
+		return group.OnEmpty, nil
+	case onEmptyFallback:
+		if group.Fallback == nil {
+			return "", errors.New("on_empty is fallback but fallback is not configured")
+		}
+
+		return onEmptyFallback, nil
+	default:
+		return "", fmt.Errorf("on_empty %s is invalid, must be keep, clear or fallback", group.OnEmpty)
+	}
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/KeisukeYamashita/slackduty/state"
+)
+
+func TestConfigureGroup_OnEmpty(t *testing.T) {
+	tcs := map[string]struct {
+		onEmpty      string
+		fallback     *config.Members
+		want         []string
+		wantDisabled bool
+		wantOutcome  state.Outcome
+		success      bool
+	}{
+		"keep by default":          {"", nil, []string{"U1"}, false, state.OutcomeSkipped, true},
+		"fallback by default":      {"", &config.Members{Slack: &config.Slack{"id:U2"}}, []string{"U2"}, false, state.OutcomeSuccess, true},
+		"keep with fallback":       {"keep", &config.Members{Slack: &config.Slack{"id:U2"}}, []string{"U1"}, false, state.OutcomeSkipped, true},
+		"clear":                    {"clear", nil, []string{"U1"}, true, state.OutcomeCleared, true},
+		"fallback without members": {"fallback", nil, []string{"U1"}, false, state.OutcomeFailed, false},
+		"invalid":                  {"drop", nil, []string{"U1"}, false, state.OutcomeFailed, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			slackClient.usergroups["handle:oncall"] = []string{"U1"}
+			store := &fakeStore{}
+			c := &Client{slack: slackClient, store: store, logger: log.NewDiscard()}
+
+			group := &config.Group{
+				Name:       "test",
+				Fallback:   tc.fallback,
+				OnEmpty:    tc.onEmpty,
+				Usergroups: []string{"handle:oncall"},
+				Members:    &config.Members{},
+			}
+
+			err := c.configureGroup(group)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if got := slackClient.usergroups["handle:oncall"]; !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members don't match got: %v want: %v", got, tc.want)
+			}
+
+			if got := slackClient.disabled["handle:oncall"]; got != tc.wantDisabled {
+				t.Fatalf("disabled doesn't match got: %v want: %v", got, tc.wantDisabled)
+			}
+
+			if got := store.records[0].Outcome; got != tc.wantOutcome {
+				t.Fatalf("outcome doesn't match got: %s want: %s", got, tc.wantOutcome)
+			}
+		})
+	}
+}
+
+func TestConfigureGroup_FallbackRefined(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:oncall"] = []string{"U1"}
+	store := &fakeStore{overrides: []state.Override{{ID: "o1", Group: "test", Action: state.ActionRemove, User: "id:U3", Until: time.Now().Add(time.Hour)}}}
+	c := &Client{slack: slackClient, store: store, logger: log.NewDiscard()}
+
+	group := &config.Group{
+		Name:       "test",
+		Exclude:    []string{"id:U4"},
+		Fallback:   &config.Members{Slack: &config.Slack{"id:U2", "id:U3", "id:U4"}},
+		OnEmpty:    "fallback",
+		Usergroups: []string{"handle:oncall"},
+		Members:    &config.Members{},
+	}
+
+	if err := c.configureGroup(group); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := slackClient.usergroups["handle:oncall"], []string{"U2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("members don't match got: %v want: %v", got, want)
+	}
+}
+
+func TestConfigureGroup_EnableDisabled(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:oncall"] = []string{"U1"}
+	slackClient.disabled["handle:oncall"] = true
+	c := &Client{slack: slackClient, logger: log.NewDiscard()}
+
+	group := &config.Group{
+		Name:       "test",
+		Usergroups: []string{"handle:oncall"},
+		Members:    &config.Members{Slack: &config.Slack{"id:U2"}},
+	}
+
+	if err := c.configureGroup(group); err != nil {
+		t.Fatal(err)
+	}
+
+	if slackClient.disabled["handle:oncall"] {
+		t.Fatal("usergroup should be enabled")
+	}
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/roster"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// getFileMembers resolves the entries of the rosters active at the execution
+// time. The Slack ID is used if the entry has it, otherwise the email.
+func (c *Client) getFileMembers(slackClient SlackClient, files []string, members *slackduty.Members) error {
+	now := c.clock()
+	eg := errgroup.Group{}
+	for _, path := range files {
+		path := path
+		eg.Go(func() error {
+			entries, err := roster.Load(path, time.Local)
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				if !entry.Active(now) {
+					continue
+				}
+
+				user := fmt.Sprintf("email:%s", entry.Email)
+				if entry.SlackID != "" {
+					user = fmt.Sprintf("id:%s", entry.SlackID)
+				}
+
+				slackUser, err := slackClient.GetUser(user)
+				if err != nil {
+					return err
+				}
+
+				member := convSlackUser(slackUser, entry.Email)
+				member.Until = entry.Until
+				members.AddFrom(fileSource(path), member)
+			}
+
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
+
+// memberFiles returns the roster files and the calendars that the members of
+// the group refer, including the fallback, the rules, the set operations and
+// the time-off files.
+func memberFiles(group *config.Group) []string {
+	files := []string{}
+	var walk func(*config.Members)
+	walk = func(m *config.Members) {
+		if m == nil {
+			return
+		}
+
+		files = append(files, m.Files...)
+		for _, cal := range m.Calendars {
+			files = append(files, cal.Path)
+		}
+		for _, members := range [][]*config.Members{m.Union, m.Intersect, m.Subtract} {
+			for _, other := range members {
+				walk(other)
+			}
+		}
+	}
+
+	walk(group.Members)
+	walk(group.Fallback)
+	for _, rule := range group.Rules {
+		walk(rule.Members)
+	}
+
+	if unavailable := group.ExcludeUnavailable; unavailable != nil {
+		walk(&config.Members{Calendars: unavailable.Calendars, Files: unavailable.Files})
+	}
+
+	return files
+}
+
+// WatchFiles syncs the groups whose roster files or calendars are changed.
+func (c *Client) WatchFiles(ctx context.Context, interval time.Duration) {
+	groups := map[string][]*config.Group{}
+	for i := range c.config.Groups {
+		group := &c.config.Groups[i]
+		for _, path := range memberFiles(group) {
+			groups[path] = append(groups[path], group)
+		}
+	}
+
+	if len(groups) == 0 {
+		return
+	}
+
+	versions := map[string]string{}
+	for path := range groups {
+		versions[path] = fileVersion(path)
+	}
+
+	c.logger.Info("start to watch the roster files", zap.Int("file count", len(groups)))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for path, old := range versions {
+				v := fileVersion(path)
+				if v == old {
+					continue
+				}
+
+				versions[path] = v
+				c.logger.Info("the roster file is changed", zap.String("file", path))
+				for _, group := range groups[path] {
+					if err := c.configureGroup(group); err != nil {
+						c.logger.Error("failed to sync the group after the roster file is changed", zap.Error(err), zap.String("group", group.Name), zap.String("file", path))
+					}
+				}
+			}
+		}
+	}
+}
+
+// fileVersion returns the version of the file by the modification time and
+// the size. It is empty if the file doesn't exist.
+func fileVersion(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/slack-go/slack"
+)
+
+func TestGetMembers_Files(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackduty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	csvPath := filepath.Join(dir, "captain.csv")
+	csv := "email,slack_id,from,until\nalice@example.com,,2020-06-01,2020-06-07\nbob@example.com,,2020-06-08,2020-06-14\n,U3,,\n"
+	if err := ioutil.WriteFile(csvPath, []byte(csv), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	yamlPath := filepath.Join(dir, "oncall.yml")
+	if err := ioutil.WriteFile(yamlPath, []byte("- email: bob@example.com\n  from: 2020-06-01\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+
+	now := time.Date(2020, 6, 3, 12, 0, 0, 0, time.Local)
+	c := &Client{slack: slackClient, now: func() time.Time { return now }, logger: log.NewDiscard()}
+
+	tcs := map[string]struct {
+		files   []string
+		want    []string
+		success bool
+	}{
+		"csv":          {[]string{csvPath}, []string{"U1", "U3"}, true},
+		"yaml":         {[]string{yamlPath}, []string{"U2"}, true},
+		"both":         {[]string{csvPath, yamlPath}, []string{"U1", "U2", "U3"}, true},
+		"missing file": {[]string{filepath.Join(dir, "missing.csv")}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			members, err := c.GetMembers(slackClient, &config.Members{Files: tc.files})
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if !tc.success {
+				return
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMemberFiles(t *testing.T) {
+	group := &config.Group{
+		Members:  &config.Members{Files: []string{"a.csv"}, Union: []*config.Members{{Files: []string{"b.csv"}}}},
+		Fallback: &config.Members{Files: []string{"c.yml"}, Calendars: []config.Calendar{{Path: "e.ics"}}},
+		Rules:    []config.Rule{{Members: &config.Members{Subtract: []*config.Members{{Files: []string{"d.yml"}}}}}},
+	}
+
+	want := []string{"a.csv", "b.csv", "c.yml", "e.ics", "d.yml"}
+	if got := memberFiles(group); !reflect.DeepEqual(got, want) {
+		t.Fatalf("files don't match got: %v want: %v", got, want)
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackduty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "roster.csv")
+	if err := ioutil.WriteFile(path, []byte("slack_id\nU1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:captain"] = []string{"U1"}
+	cfg := &config.Config{
+		Groups: []config.Group{{Name: "captain", Usergroups: []string{"handle:captain"}, Members: &config.Members{Files: []string{path}}}},
+	}
+	c := &Client{config: cfg, slack: slackClient, logger: log.NewDiscard()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.WatchFiles(ctx, 10*time.Millisecond)
+
+	if err := ioutil.WriteFile(path, []byte("slack_id\nU2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Note: The modification time is bumped on each check because the watch
+	// may start after the file is written and the file systems can have the
+	// coarse resolution.
+	deadline := time.Now().Add(5 * time.Second)
+	for i := 1; time.Now().Before(deadline); i++ {
+		mtime := time.Now().Add(time.Duration(i) * time.Second)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+
+		members, _ := slackClient.GetUsergroupMembers("handle:captain")
+		if reflect.DeepEqual(members, []string{"U2"}) {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	t.Fatal("the group is not synced after the roster file is changed")
+}
+
+// overlapSlackClient records the most usergroup updates running at once.
+type overlapSlackClient struct {
+	*fakeSlackClient
+	running int32
+	max     int32
+}
+
+func (c *overlapSlackClient) UpdateUsergroup(handle string, members string) error {
+	running := atomic.AddInt32(&c.running, 1)
+	defer atomic.AddInt32(&c.running, -1)
+
+	for {
+		max := atomic.LoadInt32(&c.max)
+		if running <= max || atomic.CompareAndSwapInt32(&c.max, max, running) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	return c.fakeSlackClient.UpdateUsergroup(handle, members)
+}
+
+func TestConfigureGroup_Serialized(t *testing.T) {
+	slackClient := &overlapSlackClient{fakeSlackClient: newFakeSlackClient()}
+	slackClient.usergroups["handle:captain"] = []string{"U2"}
+	group := &config.Group{Name: "captain", Usergroups: []string{"handle:captain"}, Members: &config.Members{Slack: &config.Slack{"id:U1"}}}
+	c := &Client{slack: slackClient, logger: log.NewDiscard()}
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.configureGroup(group); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if slackClient.max != 1 {
+		t.Fatalf("the syncs of the group overlap got: %d want: 1", slackClient.max)
+	}
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/slack-go/slack"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// userRoles are the account roles of the PagerDuty users. The names used in
+// the PagerDuty web UI are also accepted.
+var userRoles = map[string][]string{
+	"owner":                  {"owner"},
+	"admin":                  {"admin"},
+	"user":                   {"user"},
+	"limited_user":           {"limited_user"},
+	"observer":               {"observer"},
+	"restricted_access":      {"restricted_access"},
+	"read_only_user":         {"read_only_user"},
+	"read_only_limited_user": {"read_only_limited_user"},
+	"stakeholder":            {"read_only_user", "read_only_limited_user"},
+}
+
+// validateUserRoles validates the account roles of the user filter.
+func validateUserRoles(roles *config.UserRoles) error {
+	if roles == nil {
+		return nil
+	}
+
+	for _, list := range [][]string{roles.Include, roles.Exclude} {
+		for _, role := range list {
+			if _, ok := userRoles[role]; !ok {
+				return fmt.Errorf("user role %s is invalid, must be owner, admin, user, limited_user, observer, restricted_access or stakeholder", role)
+			}
+		}
+	}
+
+	return nil
+}
+
+// acceptUser reports whether the PagerDuty user passes the user filters of
+// the PagerDuty members.
+func acceptUser(pdConfig *config.Pagerduty, user *pagerduty.User) bool {
+	if pdConfig.ExcludeInvited && user.InvitationSent {
+		return false
+	}
+
+	roles := pdConfig.UserRoles
+	if roles == nil {
+		return true
+	}
+
+	if len(roles.Include) != 0 && !matchRole(roles.Include, user.Role) {
+		return false
+	}
+
+	return !matchRole(roles.Exclude, user.Role)
+}
+
+func matchRole(roles []string, role string) bool {
+	for _, r := range roles {
+		for _, apiRole := range userRoles[r] {
+			if apiRole == role {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// excludeAccounts removes the members whose Slack account is excluded by the
+// exclude_accounts of the group. The deactivated users are removed even if it
+// is not configured. The account attributes are looked up for each member, the
+// Slack client reuses the ones fetched while resolving the members.
+func (c *Client) excludeAccounts(slackClient SlackClient, group *config.Group, members *slackduty.Members) error {
+	exclude := group.ExcludeAccounts
+	if exclude == nil {
+		exclude = &config.ExcludeAccounts{}
+	}
+
+	var teamID string
+	if exclude.OtherTeams {
+		var err error
+		teamID, err = slackClient.TeamID()
+		if err != nil {
+			return fmt.Errorf("failed to get the team of the workspace error: %v", err)
+		}
+	}
+
+	eg := errgroup.Group{}
+	var mux sync.Mutex
+	excluded := map[string]string{}
+	for _, member := range members.Members {
+		member := member
+		eg.Go(func() error {
+			user, err := slackClient.GetUserInfo(member.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get the Slack user id: %s error: %v", member.ID, err)
+			}
+
+			if reason := excludedAccount(exclude, user, teamID); reason != "" {
+				mux.Lock()
+				excluded[member.ID] = reason
+				mux.Unlock()
+			}
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	for id, reason := range excluded {
+		members.RemoveBy(id, fmt.Sprintf("exclude_accounts/%s", reason))
+		c.logger.Info("excluded a member by the account", zap.String("group", group.Name), zap.String("id", id), zap.String("reason", reason))
+	}
+
+	return nil
+}
+
+// excludedAccount returns the reason why the Slack user is excluded, or
+// empty if the user is not excluded.
+func excludedAccount(exclude *config.ExcludeAccounts, user *slack.User, teamID string) string {
+	switch {
+	case (exclude.Deactivated == nil || *exclude.Deactivated) && user.Deleted:
+		return "deactivated"
+	case exclude.Bots && (user.IsBot || user.ID == "USLACKBOT"):
+		return "bot"
+	case exclude.SingleChannelGuests && user.IsUltraRestricted:
+		return "single-channel guest"
+	case exclude.MultiChannelGuests && user.IsRestricted && !user.IsUltraRestricted:
+		return "multi-channel guest"
+	case exclude.OtherTeams && teamID != "" && user.TeamID != "" && user.TeamID != teamID:
+		return "other team"
+	default:
+		return ""
+	}
+}
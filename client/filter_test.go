@@ -0,0 +1,130 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/slack-go/slack"
+)
+
+func TestAcceptUser(t *testing.T) {
+	tcs := map[string]struct {
+		cfg  *config.Pagerduty
+		user *pagerduty.User
+		want bool
+	}{
+		"no filter":           {&config.Pagerduty{}, &pagerduty.User{Role: "read_only_user", InvitationSent: true}, true},
+		"exclude stakeholder": {&config.Pagerduty{UserRoles: &config.UserRoles{Exclude: []string{"stakeholder"}}}, &pagerduty.User{Role: "read_only_limited_user"}, false},
+		"not excluded":        {&config.Pagerduty{UserRoles: &config.UserRoles{Exclude: []string{"stakeholder"}}}, &pagerduty.User{Role: "user"}, true},
+		"included":            {&config.Pagerduty{UserRoles: &config.UserRoles{Include: []string{"admin", "user"}}}, &pagerduty.User{Role: "user"}, true},
+		"not included":        {&config.Pagerduty{UserRoles: &config.UserRoles{Include: []string{"admin", "user"}}}, &pagerduty.User{Role: "limited_user"}, false},
+		"exclude invited":     {&config.Pagerduty{ExcludeInvited: true}, &pagerduty.User{Role: "user", InvitationSent: true}, false},
+		"accepted invitation": {&config.Pagerduty{ExcludeInvited: true}, &pagerduty.User{Role: "user"}, true},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if got := acceptUser(tc.cfg, tc.user); got != tc.want {
+				t.Fatalf("test %s doesn't match got: %v want: %v", n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateUserRoles(t *testing.T) {
+	tcs := map[string]struct {
+		roles   *config.UserRoles
+		success bool
+	}{
+		"nil":          {nil, true},
+		"valid":        {&config.UserRoles{Include: []string{"owner", "admin"}, Exclude: []string{"stakeholder"}}, true},
+		"invalid role": {&config.UserRoles{Exclude: []string{"manager"}}, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if err := validateUserRoles(tc.roles); (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+		})
+	}
+}
+
+func TestExcludedAccount(t *testing.T) {
+	deactivated, allowed := true, false
+	all := &config.ExcludeAccounts{Bots: true, Deactivated: &deactivated, MultiChannelGuests: true, OtherTeams: true, SingleChannelGuests: true}
+	tcs := map[string]struct {
+		exclude *config.ExcludeAccounts
+		user    *slack.User
+		want    string
+	}{
+		"member":               {all, &slack.User{ID: "U1", TeamID: "T1"}, ""},
+		"deactivated":          {all, &slack.User{ID: "U1", TeamID: "T1", Deleted: true}, "deactivated"},
+		"deactivated default":  {&config.ExcludeAccounts{Bots: true}, &slack.User{ID: "U1", TeamID: "T1", Deleted: true}, "deactivated"},
+		"deactivated allowed":  {&config.ExcludeAccounts{Deactivated: &allowed}, &slack.User{ID: "U1", TeamID: "T1", Deleted: true}, ""},
+		"bot":                  {all, &slack.User{ID: "U1", TeamID: "T1", IsBot: true}, "bot"},
+		"slackbot":             {all, &slack.User{ID: "USLACKBOT", TeamID: "T1"}, "bot"},
+		"single-channel guest": {all, &slack.User{ID: "U1", TeamID: "T1", IsRestricted: true, IsUltraRestricted: true}, "single-channel guest"},
+		"multi-channel guest":  {all, &slack.User{ID: "U1", TeamID: "T1", IsRestricted: true}, "multi-channel guest"},
+		"single-channel only":  {&config.ExcludeAccounts{SingleChannelGuests: true}, &slack.User{ID: "U1", TeamID: "T1", IsRestricted: true}, ""},
+		"other team":           {all, &slack.User{ID: "U1", TeamID: "T2"}, "other team"},
+		"other team allowed":   {&config.ExcludeAccounts{Bots: true}, &slack.User{ID: "U1", TeamID: "T2"}, ""},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if got := excludedAccount(tc.exclude, tc.user, "T1"); got != tc.want {
+				t.Fatalf("test %s doesn't match got: %q want: %q", n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetMembers_ExcludeAccounts(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:oncall"] = []string{"U1"}
+	slackClient.users["id:U2"] = &slack.User{ID: "U2", TeamID: "T1", Deleted: true}
+	slackClient.users["id:U3"] = &slack.User{ID: "U3", TeamID: "T1", IsBot: true}
+	slackClient.users["id:U4"] = &slack.User{ID: "U4", TeamID: "T2"}
+	store := &fakeStore{}
+	c := &Client{slack: slackClient, store: store, logger: log.NewDiscard()}
+
+	group := &config.Group{
+		Name:            "test",
+		ExcludeAccounts: &config.ExcludeAccounts{Bots: true, OtherTeams: true},
+		Usergroups:      []string{"handle:oncall"},
+		Members:         &config.Members{Slack: &config.Slack{"id:U1", "id:U2", "id:U3", "id:U4"}},
+	}
+
+	if err := c.configureGroup(group); err != nil {
+		t.Fatalf("failed to configure the group error: %v", err)
+	}
+
+	if got, want := slackClient.usergroups["handle:oncall"], []string{"U1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("members don't match got: %v want: %v", got, want)
+	}
+}
+
+func TestGetMembers_ExcludeDeactivated(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:oncall"] = []string{"U1"}
+	slackClient.users["id:U2"] = &slack.User{ID: "U2", TeamID: "T1", Deleted: true}
+	c := &Client{slack: slackClient, store: &fakeStore{}, logger: log.NewDiscard()}
+
+	group := &config.Group{
+		Name:       "test",
+		Usergroups: []string{"handle:oncall"},
+		Members:    &config.Members{Slack: &config.Slack{"id:U1", "id:U2"}},
+	}
+
+	if err := c.configureGroup(group); err != nil {
+		t.Fatalf("failed to configure the group error: %v", err)
+	}
+
+	if got, want := slackClient.usergroups["handle:oncall"], []string{"U1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("members don't match got: %v want: %v", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+)
+
+// guardError is returned when the sync is held back by the guard of the group.
+type guardError struct {
+	reason string
+}
+
+func (e *guardError) Error() string {
+	return fmt.Sprintf("sync is held back by the guard: %s", e.reason)
+}
+
+// checkGuard checks the members against the guard of the group. The change
+// ratio is the number of the removed members divided by the current members.
+func checkGuard(guard *config.Guard, current []string, members []slackduty.Member) error {
+	if guard == nil {
+		return nil
+	}
+
+	if guard.MinMembers > 0 && len(members) < guard.MinMembers {
+		return &guardError{reason: fmt.Sprintf("%d members are less than min_members %d", len(members), guard.MinMembers)}
+	}
+
+	if guard.MaxMembers > 0 && len(members) > guard.MaxMembers {
+		return &guardError{reason: fmt.Sprintf("%d members are more than max_members %d", len(members), guard.MaxMembers)}
+	}
+
+	if guard.MaxChangeRatio > 0 && len(current) > 0 {
+		_, removed := slackduty.Diff(current, members)
+		ratio := float64(len(removed)) / float64(len(current))
+		if ratio > guard.MaxChangeRatio {
+			return &guardError{reason: fmt.Sprintf("%d of %d members will be removed, the ratio %.2f is more than max_change_ratio %.2f", len(removed), len(current), ratio, guard.MaxChangeRatio)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"github.com/KeisukeYamashita/slackduty/state"
+)
+
+func TestCheckGuard(t *testing.T) {
+	members := []slackduty.Member{{ID: "U1"}, {ID: "U2"}}
+	tcs := map[string]struct {
+		guard   *config.Guard
+		current []string
+		held    bool
+	}{
+		"no guard":                 {nil, []string{"U3", "U4", "U5"}, false},
+		"min members":              {&config.Guard{MinMembers: 3}, nil, true},
+		"enough members":           {&config.Guard{MinMembers: 2}, nil, false},
+		"max members":              {&config.Guard{MaxMembers: 1}, nil, true},
+		"too many removed":         {&config.Guard{MaxChangeRatio: 0.5}, []string{"U1", "U3", "U4"}, true},
+		"removed within the ratio": {&config.Guard{MaxChangeRatio: 0.5}, []string{"U1", "U2", "U3"}, false},
+		"no current members":       {&config.Guard{MaxChangeRatio: 0.5}, []string{}, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			err := checkGuard(tc.guard, tc.current, members)
+			if held := err != nil; held != tc.held {
+				t.Fatalf("test %s held doesn't match got: %v want: %v error: %v", n, held, tc.held, err)
+			}
+		})
+	}
+}
+
+func TestConfigureGroup_Guard(t *testing.T) {
+	tcs := map[string]struct {
+		force bool
+		want  state.Outcome
+	}{
+		"held":   {false, state.OutcomeHeld},
+		"forced": {true, state.OutcomeSuccess},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			slackClient.usergroups["handle:oncall"] = []string{"U1", "U2", "U3"}
+			store := &fakeStore{}
+			c := &Client{slack: slackClient, store: store, force: tc.force, logger: log.NewDiscard()}
+
+			group := &config.Group{
+				Name:       "test",
+				Guard:      &config.Guard{MaxChangeRatio: 0.5},
+				Usergroups: []string{"handle:oncall"},
+				Members:    &config.Members{Slack: &config.Slack{"id:U4"}},
+			}
+
+			err := c.configureGroup(group)
+			if (err != nil) != !tc.force {
+				t.Fatalf("test %s error doesn't match: %v", n, err)
+			}
+
+			if got := store.records[0].Outcome; got != tc.want {
+				t.Fatalf("outcome doesn't match got: %s want: %s", got, tc.want)
+			}
+
+			if got := slackClient.usergroups["handle:oncall"]; tc.force != (len(got) == 1) {
+				t.Fatalf("usergroup doesn't match got: %v", got)
+			}
+		})
+	}
+}
+
+func TestConfigureGroup_ForceOnce(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.usergroups["handle:oncall"] = []string{"U1", "U2", "U3"}
+	store := &fakeStore{}
+	c := &Client{slack: slackClient, store: store, force: true, logger: log.NewDiscard()}
+
+	group := &config.Group{
+		Name:       "test",
+		Guard:      &config.Guard{MaxChangeRatio: 0.5},
+		Usergroups: []string{"handle:oncall"},
+		Members:    &config.Members{Slack: &config.Slack{"id:U4"}},
+	}
+
+	if err := c.configureGroup(group); err != nil {
+		t.Fatalf("first run should be forced error: %v", err)
+	}
+
+	group.Members = &config.Members{Slack: &config.Slack{"id:U5", "id:U6"}}
+	if err := c.configureGroup(group); err == nil {
+		t.Fatal("second run should be held by the guard")
+	}
+
+	if got := store.records[1].Outcome; got != state.OutcomeHeld {
+		t.Fatalf("outcome doesn't match got: %s want: %s", got, state.OutcomeHeld)
+	}
+}
@@ -0,0 +1,144 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"github.com/PagerDuty/go-pagerduty"
+	"golang.org/x/sync/errgroup"
+)
+
+var urgencies = map[string]bool{
+	"high": true,
+	"low":  true,
+}
+
+// validateIncidents validates the urgencies of the incidents.
+func validateIncidents(incidents *config.Incidents) error {
+	if incidents == nil {
+		return nil
+	}
+
+	for _, urgency := range incidents.Urgencies {
+		if !urgencies[urgency] {
+			return fmt.Errorf("incident urgency %s is invalid, must be high or low", urgency)
+		}
+	}
+
+	return nil
+}
+
+// hasPriority reports whether the priority of the incident is in the
+// priorities. Every incident is accepted if the priorities are empty.
+func hasPriority(priorities []string, incident pagerduty.Incident) bool {
+	if len(priorities) == 0 {
+		return true
+	}
+
+	if incident.Priority == nil {
+		return false
+	}
+
+	for _, priority := range priorities {
+		if strings.EqualFold(priority, incident.Priority.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// incidentResponders returns the IDs of the assignees, the acknowledgers and
+// the requested responders of the incident. The responders who declined the
+// request are not included.
+func incidentResponders(incident Incident) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	add := func(id string) {
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	for _, assignment := range incident.Assignments {
+		add(assignment.Assignee.ID)
+	}
+
+	for _, ack := range incident.Acknowledgements {
+		add(ack.Acknowledger.ID)
+	}
+
+	for _, request := range incident.ResponderRequests {
+		for _, target := range request.Targets {
+			for _, responder := range target.Target.Responders {
+				if responder.State != "declined" {
+					add(responder.User.ID)
+				}
+			}
+		}
+	}
+
+	return ids
+}
+
+func (c *Client) getPagerdutyIncidents(slackClient SlackClient, pdClient PagerdutyClient, pdConfig *config.Pagerduty, members *slackduty.Members) error {
+	cfg := pdConfig.Incidents
+	if cfg == nil {
+		return nil
+	}
+
+	incidents, err := pdClient.ListIncidents(cfg.Services, cfg.Urgencies)
+	if err != nil {
+		return err
+	}
+
+	eg := errgroup.Group{}
+	var mux sync.Mutex
+	pdUsers := map[string]*pagerduty.User{}
+	for _, incident := range incidents {
+		if !hasPriority(cfg.Priorities, incident.Incident) {
+			continue
+		}
+
+		incident := incident
+		for _, id := range incidentResponders(incident) {
+			id := id
+			eg.Go(func() error {
+				mux.Lock()
+				pdUser, ok := pdUsers[id]
+				mux.Unlock()
+				if !ok {
+					var err error
+					pdUser, err = pdClient.GetUser(fmt.Sprintf("id:%s", id))
+					if err != nil {
+						return err
+					}
+
+					mux.Lock()
+					pdUsers[id] = pdUser
+					mux.Unlock()
+				}
+
+				if !acceptUser(pdConfig, pdUser) {
+					return nil
+				}
+
+				slackUser, err := slackClient.GetUser(fmt.Sprintf("email:%s", pdUser.Email))
+				if err != nil {
+					return err
+				}
+
+				member := convSlackUser(slackUser, pdUser.Email)
+				members.AddFrom(pagerdutySource(pdConfig.Account, "incident", fmt.Sprintf("#%d", incident.IncidentNumber)), member)
+				return nil
+			})
+		}
+	}
+
+	return eg.Wait()
+}
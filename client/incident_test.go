@@ -0,0 +1,177 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/slack-go/slack"
+)
+
+func TestHasPriority(t *testing.T) {
+	p1 := pagerduty.Incident{Priority: &pagerduty.Priority{Name: "P1"}}
+	tcs := map[string]struct {
+		priorities []string
+		incident   pagerduty.Incident
+		want       bool
+	}{
+		"no filter":        {nil, pagerduty.Incident{}, true},
+		"matched":          {[]string{"P1", "P2"}, p1, true},
+		"case insensitive": {[]string{"p1"}, p1, true},
+		"not matched":      {[]string{"P2"}, p1, false},
+		"no priority":      {[]string{"P1"}, pagerduty.Incident{}, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if got := hasPriority(tc.priorities, tc.incident); got != tc.want {
+				t.Fatalf("test %s doesn't match got: %v want: %v", n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetMembers_Incidents(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+	slackClient.users["email:carol@example.com"] = &slack.User{ID: "U3"}
+	slackClient.users["email:dave@example.com"] = &slack.User{ID: "U4"}
+	slackClient.users["email:erin@example.com"] = &slack.User{ID: "U5"}
+
+	pdClient := newFakePagerdutyClient()
+	pdClient.users["id:P1"] = &pagerduty.User{Email: "alice@example.com"}
+	pdClient.users["id:P2"] = &pagerduty.User{Email: "bob@example.com"}
+	pdClient.users["id:P3"] = &pagerduty.User{Email: "carol@example.com", Role: "read_only_user"}
+	pdClient.users["id:P4"] = &pagerduty.User{Email: "dave@example.com"}
+	pdClient.users["id:P5"] = &pagerduty.User{Email: "erin@example.com"}
+
+	var request ResponderRequest
+	if err := json.Unmarshal([]byte(`{"responder_request_targets": [{"responder_request_target": {"type": "escalation_policy_reference", "id": "EP1", "incident_responders": [{"state": "joined", "user": {"id": "P4"}}, {"state": "declined", "user": {"id": "P5"}}]}}]}`), &request); err != nil {
+		t.Fatal(err)
+	}
+
+	pdClient.incidents = []Incident{
+		{
+			Incident: pagerduty.Incident{
+				IncidentNumber:   1,
+				Service:          pagerduty.APIObject{ID: "web"},
+				Urgency:          "high",
+				Priority:         &pagerduty.Priority{Name: "P1"},
+				Assignments:      []pagerduty.Assignment{{Assignee: pagerduty.APIObject{ID: "P1"}}},
+				Acknowledgements: []pagerduty.Acknowledgement{{Acknowledger: pagerduty.APIObject{ID: "P3"}}},
+			},
+		},
+		{
+			Incident: pagerduty.Incident{
+				IncidentNumber: 2,
+				Service:        pagerduty.APIObject{ID: "db"},
+				Urgency:        "low",
+				Assignments:    []pagerduty.Assignment{{Assignee: pagerduty.APIObject{ID: "P2"}}},
+			},
+			ResponderRequests: []ResponderRequest{request},
+		},
+	}
+
+	c := &Client{pagerduty: pdClient, slack: slackClient, logger: log.NewDiscard()}
+
+	tcs := map[string]struct {
+		cfg     *config.Pagerduty
+		want    []string
+		success bool
+	}{
+		"all incidents":     {&config.Pagerduty{Incidents: &config.Incidents{}}, []string{"U1", "U2", "U3", "U4"}, true},
+		"service":           {&config.Pagerduty{Incidents: &config.Incidents{Services: []string{"id:db"}}}, []string{"U2", "U4"}, true},
+		"urgency":           {&config.Pagerduty{Incidents: &config.Incidents{Urgencies: []string{"high"}}}, []string{"U1", "U3"}, true},
+		"priority":          {&config.Pagerduty{Incidents: &config.Incidents{Priorities: []string{"P1"}}}, []string{"U1", "U3"}, true},
+		"filter user roles": {&config.Pagerduty{Incidents: &config.Incidents{}, UserRoles: &config.UserRoles{Exclude: []string{"stakeholder"}}}, []string{"U1", "U2", "U4"}, true},
+		"invalid urgency":   {&config.Pagerduty{Incidents: &config.Incidents{Urgencies: []string{"urgent"}}}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			members, err := c.GetMembers(slackClient, &config.Members{Pagerduty: config.Pagerduties{tc.cfg}})
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if !tc.success {
+				return
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPagerdutyClient_ListIncidents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Token token=test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": {"message": "Unauthorized"}}`)
+			return
+		}
+
+		q := r.URL.Query()
+		if r.URL.Path != "/incidents" || q.Get("date_range") != "all" || !reflect.DeepEqual(q["statuses[]"], []string{"triggered", "acknowledged"}) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": {"message": "Invalid Input Provided"}}`)
+			return
+		}
+
+		switch q.Get("offset") {
+		case "0":
+			fmt.Fprint(w, `{"incidents": [{"id": "I1", "incident_number": 1, "assignments": [{"assignee": {"id": "P1"}}]}], "more": true}`)
+		default:
+			fmt.Fprint(w, `{"incidents": [{"id": "I2", "incident_number": 2, "responder_requests": [{"responder_request_targets": [{"responder_request_target": {"type": "user_reference", "id": "P2", "incident_responders": [{"state": "pending", "user": {"id": "P2"}}]}}]}]}], "more": false}`)
+		}
+	}))
+	defer server.Close()
+
+	tcs := map[string]struct {
+		apiKey  string
+		want    [][]string
+		success bool
+	}{
+		"responder requests": {"test", [][]string{{"P1"}, {"P2"}}, true},
+		"unauthorized":       {"invalid", nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			c := &pagerdutyClient{apiKey: tc.apiKey, url: server.URL, http: server.Client()}
+			incidents, err := c.ListIncidents(nil, nil)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if !tc.success {
+				return
+			}
+
+			got := [][]string{}
+			for _, incident := range incidents {
+				got = append(got, incidentResponders(incident))
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("responders don't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"go.uber.org/zap"
+)
+
+// notifyMembers sends a direct message to the users who are added to or
+// removed from the usergroup. The members tell the end of the shift of the
+// added users. Failing to notify a user doesn't fail the sync.
+func (c *Client) notifyMembers(slackClient SlackClient, group *config.Group, usergroup string, members []slackduty.Member, added, removed []string) {
+	name := usergroupLabel(usergroup)
+
+	until := map[string]time.Time{}
+	for _, member := range members {
+		until[member.ID] = member.Until
+	}
+
+	if group.Notify.Added {
+		now := c.clock()
+		for _, id := range added {
+			text := fmt.Sprintf("You're now in %s (%s)%s.", name, group.Name, shiftEnd(until[id], now))
+			if err := slackClient.PostMessage(id, text); err != nil {
+				c.logger.Warn("failed to notify the added member", zap.Error(err), zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("user", id))
+			}
+		}
+	}
+
+	if group.Notify.Removed {
+		for _, id := range removed {
+			text := fmt.Sprintf("You're no longer in %s (%s).", name, group.Name)
+			if err := slackClient.PostMessage(id, text); err != nil {
+				c.logger.Warn("failed to notify the removed member", zap.Error(err), zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("user", id))
+			}
+		}
+	}
+}
+
+// shiftEnd returns the end of the shift for the message in the location of
+// now, e.g. ` until Friday 10:00`. It is empty if the end is unknown.
+func shiftEnd(until, now time.Time) string {
+	if until.IsZero() {
+		return ""
+	}
+
+	until = until.In(now.Location())
+	if until.Sub(now) < 6*24*time.Hour {
+		return " until " + until.Format("Monday 15:04")
+	}
+
+	return " until " + until.Format("Monday, Jan 2 15:04")
+}
+
+// usergroupLabel returns the human readable name of the usergroup. It is not
+// a mention so that the message doesn't notify the whole usergroup.
+func usergroupLabel(usergroup string) string {
+	s := strings.Split(usergroup, ":")
+	if len(s) != 2 {
+		return usergroup
+	}
+
+	if s[0] == "handle" {
+		return "@" + s[1]
+	}
+
+	return s[1]
+}
@@ -0,0 +1,274 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultOpsgenieURL     = "https://api.opsgenie.com"
+	defaultOpsgenieTimeout = 10 * time.Second
+)
+
+// OpsgenieClient is a interface that the Opsgenie client should implement
+type OpsgenieClient interface {
+	GetScheduledUser(string) ([]OpsgenieUser, error)
+	GetTeam(string) ([]OpsgenieUser, error)
+	GetUser(string) (*OpsgenieUser, error)
+}
+
+// OpsgenieUser is a Opsgenie user. The username is the email of the user.
+type OpsgenieUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+var _ OpsgenieClient = (*opsgenieClient)(nil)
+
+type opsgenieClient struct {
+	mux    sync.RWMutex
+	apiKey string
+	url    string
+	client *http.Client
+}
+
+type opsgenieOptions struct {
+	url string
+}
+
+// OpsgenieOption configures the Opsgenie client
+type OpsgenieOption func(*opsgenieOptions)
+
+// WithOpsgenieURL changes the URL of the Opsgenie API(e.g.
+// `https://api.eu.opsgenie.com` for the EU instance).
+func WithOpsgenieURL(url string) OpsgenieOption {
+	return func(o *opsgenieOptions) {
+		o.url = url
+	}
+}
+
+// client creates the Opsgenie API client of the account.
+func (a *opsgenieAccount) client() OpsgenieClient {
+	opts := []OpsgenieOption{}
+	if a.url != "" {
+		opts = append(opts, WithOpsgenieURL(a.url))
+	}
+
+	return NewOpsgenieClient(a.apiKey, opts...)
+}
+
+// NewOpsgenieClient creates a new Opsgenie API client
+func NewOpsgenieClient(apiKey string, opts ...OpsgenieOption) OpsgenieClient {
+	o := opsgenieOptions{url: defaultOpsgenieURL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &opsgenieClient{
+		apiKey: apiKey,
+		url:    strings.TrimSuffix(o.url, "/"),
+		client: &http.Client{Timeout: defaultOpsgenieTimeout},
+	}
+}
+
+// SetAPIKey replaces the API key of the client. It is used when the API key
+// is rotated while Slackduty is running.
+func (c *opsgenieClient) SetAPIKey(apiKey string) {
+	c.mux.Lock()
+	c.apiKey = apiKey
+	c.mux.Unlock()
+}
+
+// GetScheduledUser returns the users on call of the schedule at the
+// execution time.
+func (c *opsgenieClient) GetScheduledUser(schedule string) ([]OpsgenieUser, error) {
+	kind, val, err := opsgenieSelector("schedule", schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		OnCallRecipients []string `json:"onCallRecipients"`
+	}
+
+	query := url.Values{"scheduleIdentifierType": {kind}, "flat": {"true"}}
+	if err := c.get(fmt.Sprintf("/v2/schedules/%s/on-calls", url.PathEscape(val)), query, &data); err != nil {
+		return nil, err
+	}
+
+	users := []OpsgenieUser{}
+	for _, username := range data.OnCallRecipients {
+		users = append(users, OpsgenieUser{Username: username})
+	}
+
+	return users, nil
+}
+
+// GetTeam returns the members of the team.
+func (c *opsgenieClient) GetTeam(team string) ([]OpsgenieUser, error) {
+	kind, val, err := opsgenieSelector("team", team)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		Members []struct {
+			User OpsgenieUser `json:"user"`
+		} `json:"members"`
+	}
+
+	query := url.Values{"identifierType": {kind}}
+	if err := c.get(fmt.Sprintf("/v2/teams/%s", url.PathEscape(val)), query, &data); err != nil {
+		return nil, err
+	}
+
+	users := []OpsgenieUser{}
+	for _, member := range data.Members {
+		users = append(users, member.User)
+	}
+
+	return users, nil
+}
+
+func (c *opsgenieClient) GetUser(user string) (*OpsgenieUser, error) {
+	s := strings.Split(user, ":")
+	if len(s) != 2 {
+		return nil, fmt.Errorf("user is specified in wrong format user: %s", user)
+	}
+
+	switch s[0] {
+	case "id", "email":
+	default:
+		return nil, fmt.Errorf("user kind %s is invalid, must be email or id for user:%s", s[0], user)
+	}
+
+	var data OpsgenieUser
+	if err := c.get(fmt.Sprintf("/v2/users/%s", url.PathEscape(s[1])), nil, &data); err != nil {
+		return nil, err
+	}
+
+	return &data, nil
+}
+
+// opsgenieSelector returns the identifier type of the Opsgenie API and the
+// value of the selector.
+func opsgenieSelector(resource, selector string) (string, string, error) {
+	s := strings.Split(selector, ":")
+	if len(s) != 2 {
+		return "", "", fmt.Errorf("%s is specified in wrong format %s: %s", resource, resource, selector)
+	}
+
+	switch s[0] {
+	case "id", "name":
+		return s[0], s[1], nil
+	default:
+		return "", "", fmt.Errorf("%s kind %s is invalid, must be id or name for %s:%s", resource, s[0], resource, selector)
+	}
+}
+
+// get calls the Opsgenie API and decodes the data of the response.
+func (c *opsgenieClient) get(path string, query url.Values, data interface{}) error {
+	c.mux.RLock()
+	apiKey := c.apiKey
+	c.mux.RUnlock()
+
+	u := c.url + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "GenieKey "+apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+		return fmt.Errorf("opsgenie returned status code %d for path: %s message: %s", resp.StatusCode, path, body.Message)
+	}
+
+	body := struct {
+		Data interface{} `json:"data"`
+	}{Data: data}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode the opsgenie response for path: %s error: %v", path, err)
+	}
+
+	return nil
+}
+
+// getOpsgenieMembers resolves the Opsgenie schedules, teams and users to the
+// Slack users by the email.
+func (c *Client) getOpsgenieMembers(slackClient SlackClient, ogClient OpsgenieClient, ogConfig *config.Opsgenie, members *slackduty.Members) error {
+	eg := errgroup.Group{}
+	add := func(kind, selector string, users []OpsgenieUser) error {
+		for _, user := range users {
+			slackUser, err := slackClient.GetUser(fmt.Sprintf("email:%s", user.Username))
+			if err != nil {
+				return err
+			}
+
+			members.AddFrom(opsgenieSource(ogConfig.Account, kind, selector), convSlackUser(slackUser, user.Username))
+		}
+
+		return nil
+	}
+
+	for _, schedule := range ogConfig.Schedules {
+		schedule := schedule
+		eg.Go(func() error {
+			users, err := ogClient.GetScheduledUser(schedule)
+			if err != nil {
+				return err
+			}
+
+			return add("schedule", schedule, users)
+		})
+	}
+
+	for _, team := range ogConfig.Teams {
+		team := team
+		eg.Go(func() error {
+			users, err := ogClient.GetTeam(team)
+			if err != nil {
+				return err
+			}
+
+			return add("team", team, users)
+		})
+	}
+
+	for _, user := range ogConfig.Users {
+		user := user
+		eg.Go(func() error {
+			ogUser, err := ogClient.GetUser(user)
+			if err != nil {
+				return err
+			}
+
+			return add("user", user, []OpsgenieUser{*ogUser})
+		})
+	}
+
+	return eg.Wait()
+}
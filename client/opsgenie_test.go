@@ -0,0 +1,138 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/slack-go/slack"
+)
+
+// newOpsgenieServer is a stand-in of the Opsgenie API.
+func newOpsgenieServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "GenieKey test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message": "Could not authenticate"}`)
+			return
+		}
+
+		q := r.URL.Query()
+		switch {
+		case r.URL.Path == "/v2/schedules/web-oncall/on-calls" && q.Get("scheduleIdentifierType") == "name" && q.Get("flat") == "true":
+			fmt.Fprint(w, `{"data": {"onCallRecipients": ["alice@example.com"]}}`)
+		case r.URL.Path == "/v2/teams/web" && q.Get("identifierType") == "name":
+			fmt.Fprint(w, `{"data": {"members": [{"user": {"id": "u1", "username": "alice@example.com"}, "role": "admin"}, {"user": {"id": "u2", "username": "bob@example.com"}, "role": "user"}]}}`)
+		case r.URL.Path == "/v2/users/carol@example.com":
+			fmt.Fprint(w, `{"data": {"id": "u3", "username": "carol@example.com"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not found"}`)
+		}
+	}))
+}
+
+func TestOpsgenieClient(t *testing.T) {
+	server := newOpsgenieServer()
+	defer server.Close()
+
+	c := NewOpsgenieClient("test", WithOpsgenieURL(server.URL))
+
+	tcs := map[string]struct {
+		get     func() ([]OpsgenieUser, error)
+		want    []OpsgenieUser
+		success bool
+	}{
+		"schedule": {func() ([]OpsgenieUser, error) { return c.GetScheduledUser("name:web-oncall") }, []OpsgenieUser{{Username: "alice@example.com"}}, true},
+		"team":     {func() ([]OpsgenieUser, error) { return c.GetTeam("name:web") }, []OpsgenieUser{{ID: "u1", Username: "alice@example.com"}, {ID: "u2", Username: "bob@example.com"}}, true},
+		"user": {func() ([]OpsgenieUser, error) {
+			u, err := c.GetUser("email:carol@example.com")
+			if err != nil {
+				return nil, err
+			}
+			return []OpsgenieUser{*u}, nil
+		}, []OpsgenieUser{{ID: "u3", Username: "carol@example.com"}}, true},
+		"not found":    {func() ([]OpsgenieUser, error) { return c.GetTeam("id:unknown") }, nil, false},
+		"wrong format": {func() ([]OpsgenieUser, error) { return c.GetScheduledUser("web-oncall") }, nil, false},
+		"invalid kind": {func() ([]OpsgenieUser, error) { return c.GetTeam("email:web") }, nil, false},
+		"invalid user": {func() ([]OpsgenieUser, error) { _, err := c.GetUser("name:carol"); return nil, err }, nil, false},
+		"invalid apikey": {func() ([]OpsgenieUser, error) {
+			return NewOpsgenieClient("wrong", WithOpsgenieURL(server.URL)).GetTeam("name:web")
+		}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := tc.get()
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if tc.success && !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("users don't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetMembers_Opsgenie(t *testing.T) {
+	server := newOpsgenieServer()
+	defer server.Close()
+
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+	slackClient.users["email:carol@example.com"] = &slack.User{ID: "U3"}
+
+	c := &Client{
+		opsgenie:  NewOpsgenieClient("test", WithOpsgenieURL(server.URL)),
+		opsgenies: map[string]OpsgenieClient{"eu": NewOpsgenieClient("test", WithOpsgenieURL(server.URL))},
+		slack:     slackClient,
+		logger:    log.NewDiscard(),
+	}
+
+	tcs := map[string]struct {
+		cfg     config.Opsgenies
+		want    []string
+		success bool
+	}{
+		"schedule":        {config.Opsgenies{{Schedules: []string{"name:web-oncall"}}}, []string{"U1"}, true},
+		"team":            {config.Opsgenies{{Teams: []string{"name:web"}}}, []string{"U1", "U2"}, true},
+		"user":            {config.Opsgenies{{Users: []string{"email:carol@example.com"}}}, []string{"U3"}, true},
+		"accounts":        {config.Opsgenies{{Schedules: []string{"name:web-oncall"}}, {Account: "eu", Users: []string{"email:carol@example.com"}}}, []string{"U1", "U3"}, true},
+		"unknown account": {config.Opsgenies{{Account: "us", Teams: []string{"name:web"}}}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			members, err := c.GetMembers(slackClient, &config.Members{Opsgenie: tc.cfg})
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if !tc.success {
+				return
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+
+	c.opsgenie = nil
+	if _, err := c.GetMembers(slackClient, &config.Members{Opsgenie: config.Opsgenies{{Teams: []string{"name:web"}}}}); err == nil {
+		t.Fatal("members are resolved without the Opsgenie API")
+	}
+}
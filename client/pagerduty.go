@@ -1,9 +1,15 @@
 package client
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/PagerDuty/go-pagerduty"
 	"golang.org/x/sync/errgroup"
@@ -12,15 +18,45 @@ import (
 // PagerdutyClient is a interface that the PagerDuty client should implement
 type PagerdutyClient interface {
 	GetScheduledUser(string) ([]pagerduty.User, error)
-	GetService(string) ([]pagerduty.User, error)
-	GetTeam(string) ([]pagerduty.User, error)
+	GetService(string, []string) ([]pagerduty.User, error)
+	GetTeam(string, []string) ([]pagerduty.User, error)
 	GetUser(string) (*pagerduty.User, error)
+	ListIncidents([]string, []string) ([]Incident, error)
+	RenderSchedule(string, time.Time, time.Time) (*pagerduty.Schedule, error)
+}
+
+const (
+	defaultPagerdutyURL     = "https://api.pagerduty.com"
+	defaultPagerdutyTimeout = 10 * time.Second
+	incidentsLimit          = 100
+)
+
+// Incident is a PagerDuty incident with the responder requests that the
+// PagerDuty client library doesn't decode.
+type Incident struct {
+	pagerduty.Incident
+	ResponderRequests []ResponderRequest `json:"responder_requests,omitempty"`
+}
+
+// ResponderRequest is a request to join the incident. Each target has the
+// responders requested by it(e.g. the users of the escalation policy).
+type ResponderRequest struct {
+	Targets []struct {
+		Target struct {
+			pagerduty.APIObject
+			Responders []pagerduty.IncidentResponders `json:"incident_responders"`
+		} `json:"responder_request_target"`
+	} `json:"responder_request_targets"`
 }
 
 var _ PagerdutyClient = (*pagerdutyClient)(nil)
 
 type pagerdutyClient struct {
+	mux    sync.RWMutex
 	client *pagerduty.Client
+	apiKey string
+	url    string
+	http   *http.Client
 }
 
 // NewPagerDutyClient creates a new PagerDuty API client
@@ -28,10 +64,58 @@ func NewPagerDutyClient(apiKey string) PagerdutyClient {
 	client := pagerduty.NewClient(apiKey)
 	return &pagerdutyClient{
 		client: client,
+		apiKey: apiKey,
+		url:    defaultPagerdutyURL,
+		http:   &http.Client{Timeout: defaultPagerdutyTimeout},
 	}
 }
 
+// SetAPIKey replaces the API key of the client. It is used when the API key
+// is rotated while Slackduty is running.
+func (c *pagerdutyClient) SetAPIKey(apiKey string) {
+	c.mux.Lock()
+	c.client = pagerduty.NewClient(apiKey)
+	c.apiKey = apiKey
+	c.mux.Unlock()
+}
+
+func (c *pagerdutyClient) api() *pagerduty.Client {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.client
+}
+
 func (c *pagerdutyClient) GetScheduledUser(schedule string) ([]pagerduty.User, error) {
+	pdSche, err := c.getSchedule(schedule, pagerduty.GetScheduleOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	users := []pagerduty.User{}
+	for _, apiObj := range pdSche.Users {
+		opt := pagerduty.GetUserOptions{}
+		user, err := c.api().GetUser(apiObj.ID, opt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, nil
+}
+
+// RenderSchedule returns the schedule with the entries rendered between the
+// since and the until.
+func (c *pagerdutyClient) RenderSchedule(schedule string, since, until time.Time) (*pagerduty.Schedule, error) {
+	opt := pagerduty.GetScheduleOptions{
+		Since: since.Format(time.RFC3339),
+		Until: until.Format(time.RFC3339),
+	}
+
+	return c.getSchedule(schedule, opt)
+}
+
+func (c *pagerdutyClient) getSchedule(schedule string, opt pagerduty.GetScheduleOptions) (*pagerduty.Schedule, error) {
 	s := strings.Split(schedule, ":")
 	if len(s) != 2 {
 		return nil, fmt.Errorf("schedule is specified in wrong format schedule: %s", schedule)
@@ -40,15 +124,12 @@ func (c *pagerdutyClient) GetScheduledUser(schedule string) ([]pagerduty.User, e
 	kind := s[0]
 	val := s[1]
 
-	var pdSche *pagerduty.Schedule
-	var err error
 	switch kind {
 	case "id":
-		opt := pagerduty.GetScheduleOptions{}
-		pdSche, err = c.client.GetSchedule(val, opt)
+		return c.api().GetSchedule(val, opt)
 	case "name":
-		opt := pagerduty.ListSchedulesOptions{Query: val}
-		resp, err := c.client.ListSchedules(opt)
+		listOpt := pagerduty.ListSchedulesOptions{Query: val}
+		resp, err := c.api().ListSchedules(listOpt)
 		if err != nil {
 			return nil, err
 		}
@@ -64,29 +145,50 @@ func (c *pagerdutyClient) GetScheduledUser(schedule string) ([]pagerduty.User, e
 			}
 		}
 
-		pdSche = &pdSches[0]
+		if opt.Since == "" && opt.Until == "" {
+			return &pdSches[0], nil
+		}
+
+		return c.api().GetSchedule(pdSches[0].ID, opt)
 	default:
 		return nil, fmt.Errorf("schedule kind %s is invalid, must be email, id or name for schedule:%s", kind, schedule)
 	}
+}
 
+// GetService returns the members of the teams of the service. If the roles
+// are given, only the team members with the roles are returned.
+func (c *pagerdutyClient) GetService(service string, roles []string) ([]pagerduty.User, error) {
+	pdSvc, err := c.getService(service)
 	if err != nil {
 		return nil, err
 	}
 
+	eg := errgroup.Group{}
+	var mux sync.Mutex
 	users := []pagerduty.User{}
-	for _, apiObj := range pdSche.Users {
-		opt := pagerduty.GetUserOptions{}
-		user, err := c.client.GetUser(apiObj.ID, opt)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, *user)
+	for _, team := range pdSvc.Teams {
+		team := team
+		eg.Go(func() error {
+			svcUsers, err := c.GetTeam(fmt.Sprintf("id:%s", team.ID), roles)
+			if err != nil {
+				return err
+			}
+
+			mux.Lock()
+			users = append(users, svcUsers...)
+			mux.Unlock()
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
 	}
 
 	return users, nil
 }
 
-func (c *pagerdutyClient) GetService(service string) ([]pagerduty.User, error) {
+func (c *pagerdutyClient) getService(service string) (*pagerduty.Service, error) {
 	s := strings.Split(service, ":")
 	if len(s) != 2 {
 		return nil, fmt.Errorf("service is specified in wrong format service: %s", service)
@@ -95,15 +197,13 @@ func (c *pagerdutyClient) GetService(service string) ([]pagerduty.User, error) {
 	kind := s[0]
 	val := s[1]
 
-	var pdSvc *pagerduty.Service
-	var err error
 	switch kind {
 	case "id":
 		opt := pagerduty.GetServiceOptions{}
-		pdSvc, err = c.client.GetService(val, &opt)
+		return c.api().GetService(val, &opt)
 	case "name":
 		opt := pagerduty.ListServiceOptions{Query: val}
-		resp, err := c.client.ListServices(opt)
+		resp, err := c.api().ListServices(opt)
 		if err != nil {
 			return nil, err
 		}
@@ -119,41 +219,100 @@ func (c *pagerdutyClient) GetService(service string) ([]pagerduty.User, error) {
 			}
 		}
 
-		pdSvc = &pdSvcs[0]
+		return &pdSvcs[0], nil
 	default:
 		return nil, fmt.Errorf("service kind %s is invalid, must be email, id or name for service:%s", kind, service)
 	}
+}
 
+// ListIncidents returns the triggered or acknowledged incidents of any date.
+// If the services or the urgencies are given, only the incidents of them are
+// returned. The API is called directly because the PagerDuty client library
+// doesn't decode the responder requests.
+func (c *pagerdutyClient) ListIncidents(services []string, urgencies []string) ([]Incident, error) {
+	query := url.Values{
+		"statuses[]": []string{"triggered", "acknowledged"},
+		"date_range": []string{"all"},
+		"limit":      []string{strconv.Itoa(incidentsLimit)},
+	}
+
+	for _, urgency := range urgencies {
+		query.Add("urgencies[]", urgency)
+	}
+
+	for _, service := range services {
+		pdSvc, err := c.getService(service)
+		if err != nil {
+			return nil, err
+		}
+		query.Add("service_ids[]", pdSvc.ID)
+	}
+
+	incidents := []Incident{}
+	for offset := 0; ; {
+		query.Set("offset", strconv.Itoa(offset))
+
+		var resp struct {
+			Incidents []Incident `json:"incidents"`
+			More      bool       `json:"more"`
+		}
+		if err := c.get("/incidents", query, &resp); err != nil {
+			return nil, err
+		}
+
+		incidents = append(incidents, resp.Incidents...)
+		if !resp.More || len(resp.Incidents) == 0 {
+			return incidents, nil
+		}
+
+		offset += len(resp.Incidents)
+	}
+}
+
+// get calls the PagerDuty REST API and decodes the response.
+func (c *pagerdutyClient) get(path string, query url.Values, data interface{}) error {
+	c.mux.RLock()
+	apiKey := c.apiKey
+	c.mux.RUnlock()
+
+	u := c.url + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+	req.Header.Set("Authorization", "Token token="+apiKey)
 
-	eg := errgroup.Group{}
-	var mux sync.Mutex
-	users := []pagerduty.User{}
-	for _, team := range pdSvc.Teams {
-		team := team
-		eg.Go(func() error {
-			svcUsers, err := c.GetTeam(fmt.Sprintf("id:%s", team.ID))
-			if err != nil {
-				return err
-			}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-			mux.Lock()
-			users = append(users, svcUsers...)
-			mux.Unlock()
-			return nil
-		})
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body)
+		return fmt.Errorf("pagerduty returned status code %d for path: %s message: %s", resp.StatusCode, path, body.Error.Message)
 	}
 
-	if err := eg.Wait(); err != nil {
-		return nil, err
+	if err := json.NewDecoder(resp.Body).Decode(data); err != nil {
+		return fmt.Errorf("failed to decode the pagerduty response for path: %s error: %v", path, err)
 	}
 
-	return users, nil
+	return nil
 }
 
-func (c *pagerdutyClient) GetTeam(team string) ([]pagerduty.User, error) {
+// GetTeam returns the members of the team. If the roles are given, only the
+// members with the roles(manager, responder or observer) are returned.
+func (c *pagerdutyClient) GetTeam(team string, roles []string) ([]pagerduty.User, error) {
 	s := strings.Split(team, ":")
 	if len(s) != 2 {
 		return nil, fmt.Errorf("team is specified in wrong format team: %s", team)
@@ -166,10 +325,10 @@ func (c *pagerdutyClient) GetTeam(team string) ([]pagerduty.User, error) {
 	var err error
 	switch kind {
 	case "id":
-		members, err = c.client.ListAllMembers(val)
+		members, err = c.api().ListAllMembers(val)
 	case "name":
 		opt := pagerduty.ListTeamOptions{Query: val}
-		resp, err := c.client.ListTeams(opt)
+		resp, err := c.api().ListTeams(opt)
 		if err != nil {
 			return nil, err
 		}
@@ -186,7 +345,7 @@ func (c *pagerdutyClient) GetTeam(team string) ([]pagerduty.User, error) {
 		}
 
 		id := &teams[0].ID
-		members, err = c.client.ListAllMembers(*id)
+		members, err = c.api().ListAllMembers(*id)
 	default:
 		return nil, fmt.Errorf("team kind %s is invalid, must be email, id or name for team:%s", kind, team)
 	}
@@ -197,8 +356,12 @@ func (c *pagerdutyClient) GetTeam(team string) ([]pagerduty.User, error) {
 
 	users := []pagerduty.User{}
 	for _, member := range members {
+		if !hasRole(roles, member.Role) {
+			continue
+		}
+
 		opt := pagerduty.GetUserOptions{}
-		user, err := c.client.GetUser(member.APIObject.ID, opt)
+		user, err := c.api().GetUser(member.APIObject.ID, opt)
 		if err != nil {
 			return nil, err
 		}
@@ -220,10 +383,10 @@ func (c *pagerdutyClient) GetUser(user string) (*pagerduty.User, error) {
 	switch kind {
 	case "id":
 		opt := pagerduty.GetUserOptions{Includes: []string{"contact_methods"}}
-		return c.client.GetUser(val, opt)
+		return c.api().GetUser(val, opt)
 	case "name", "email":
 		opt := pagerduty.ListUsersOptions{Query: val, Includes: []string{"contact_methods"}}
-		resp, err := c.client.ListUsers(opt)
+		resp, err := c.api().ListUsers(opt)
 		if err != nil {
 			return nil, err
 		}
@@ -244,3 +407,36 @@ func (c *pagerdutyClient) GetUser(user string) (*pagerduty.User, error) {
 		return nil, fmt.Errorf("user kind %s is invalid, must be email, id or name for user:%s", kind, user)
 	}
 }
+
+var teamRoles = map[string]bool{
+	"manager":   true,
+	"responder": true,
+	"observer":  true,
+}
+
+// validateTeamRoles validates the roles of the team members.
+func validateTeamRoles(roles []string) error {
+	for _, role := range roles {
+		if !teamRoles[role] {
+			return fmt.Errorf("team role %s is invalid, must be manager, responder or observer", role)
+		}
+	}
+
+	return nil
+}
+
+// hasRole reports whether the role is in the roles. Every role is accepted if
+// the roles are empty.
+func hasRole(roles []string, role string) bool {
+	if len(roles) == 0 {
+		return true
+	}
+
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+)
+
+// Action is what the sync will do to the usergroup.
+type Action string
+
+const (
+	// ActionUpdate updates the members of the usergroup.
+	ActionUpdate Action = "update"
+	// ActionSkip leaves the usergroup as it is because nobody is resolved.
+	ActionSkip Action = "skip"
+	// ActionClear disables the usergroup because nobody is resolved.
+	ActionClear Action = "clear"
+	// ActionDisable disables the usergroup because it is outside of the hours.
+	ActionDisable Action = "disable"
+	// ActionHold leaves the usergroup as it is because of the guard.
+	ActionHold Action = "hold"
+	// ActionPaused leaves the usergroup as it is because the group is
+	// paused(e.g. by the rollback).
+	ActionPaused Action = "paused"
+)
+
+// Plan is the change that the sync of the group will apply to a usergroup.
+// Members have the sources that they are resolved from. Excluded are the
+// resolved users that are removed by the excludes or the overrides.
+type Plan struct {
+	Workspace string
+	Usergroup string
+	Action    Action
+	Reason    string
+	Members   []slackduty.Member
+	Excluded  []slackduty.Member
+	Current   []string
+	Added     []string
+	Removed   []string
+}
+
+// Plan resolves the members of the group without updating the usergroups.
+func (c *Client) Plan(key string) ([]Plan, error) {
+	group, err := c.config.FindGroup(key)
+	if err != nil {
+		return nil, err
+	}
+
+	pause, err := c.paused(group)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := group.Workspaces
+	if len(workspaces) == 0 {
+		workspaces = []string{defaultWorkspace}
+	}
+
+	plans := []Plan{}
+	for _, workspace := range workspaces {
+		slackClient, err := c.slackClient(workspace)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := c.preCheck(slackClient, group); err != nil {
+			return nil, fmt.Errorf("precheck failed workspace: %s error: %v", workspace, err)
+		}
+
+		outside, err := disabledHours(group, c.clock())
+		if err != nil {
+			return nil, err
+		}
+
+		var members *slackduty.Members
+		var clear bool
+		if !outside {
+			members, clear, err = c.resolveMembers(slackClient, group, workspace)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, usergroup := range group.Usergroups {
+			current, err := c.currentMembers(slackClient, group, workspace, usergroup)
+			if err != nil {
+				return nil, err
+			}
+
+			plan := Plan{
+				Workspace: workspace,
+				Usergroup: usergroup,
+				Current:   current,
+			}
+
+			switch {
+			case outside:
+				plan.Action = ActionDisable
+			case len(members.Members) == 0 && clear:
+				plan.Action = ActionClear
+				_, plan.Removed = slackduty.Diff(current, nil)
+			case len(members.Members) == 0:
+				plan.Action = ActionSkip
+			default:
+				plan.Action = ActionUpdate
+				plan.Members = members.Members
+				plan.Added, plan.Removed = slackduty.Diff(current, members.Members)
+			}
+
+			if members != nil {
+				plan.Excluded = excluded(members)
+			}
+
+			if plan.Action == ActionUpdate || plan.Action == ActionClear {
+				if err := checkGuard(group.Guard, current, plan.Members); err != nil && !c.forcing(group) {
+					plan.Action = ActionHold
+					plan.Reason = err.Error()
+				}
+			}
+
+			// Note: The members are still resolved so that it shows what the
+			// sync will apply once the group is resumed.
+			if pause != nil {
+				plan.Action = ActionPaused
+				plan.Reason = fmt.Sprintf("paused by %s run id: %s at %s", pause.Reason, pause.RunID, pause.Timestamp.Format(time.RFC3339))
+				plan.Added, plan.Removed = nil, nil
+			}
+
+			plans = append(plans, plan)
+		}
+	}
+
+	return plans, nil
+}
+
+// Reason explains why the user is or isn't a member of a usergroup.
+// RemovedBy is the step that removed the user resolved from the sources(e.g.
+// `exclude`). Paused is the reason of the plan if the group is paused.
+type Reason struct {
+	Workspace string
+	Usergroup string
+	Member    bool
+	Current   bool
+	Sources   []string
+	RemovedBy string
+	Paused    string
+}
+
+// Why explains why the user(e.g. `email:alice@example.com`) is or isn't a
+// member of each usergroup of the group.
+func (c *Client) Why(key, user string) ([]Reason, error) {
+	plans, err := c.Plan(key)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := []Reason{}
+	for _, plan := range plans {
+		slackClient, err := c.slackClient(plan.Workspace)
+		if err != nil {
+			return nil, err
+		}
+
+		id, email, err := userID(slackClient, user)
+		if err != nil {
+			return nil, err
+		}
+
+		reason := Reason{
+			Workspace: plan.Workspace,
+			Usergroup: plan.Usergroup,
+		}
+
+		if plan.Action == ActionPaused {
+			reason.Paused = plan.Reason
+		}
+
+		for _, current := range plan.Current {
+			if current == id {
+				reason.Current = true
+			}
+		}
+
+		for _, member := range plan.Members {
+			if matchUser(member, id, email) {
+				reason.Member = true
+				reason.Sources = member.Sources
+			}
+		}
+
+		if !reason.Member {
+			for _, member := range plan.Excluded {
+				if matchUser(member, id, email) {
+					reason.Sources = member.Sources
+					reason.RemovedBy = member.RemovedBy
+				}
+			}
+		}
+
+		reasons = append(reasons, reason)
+	}
+
+	return reasons, nil
+}
+
+// excluded returns the removed members that are not added back(e.g. by the
+// override).
+func excluded(members *slackduty.Members) []slackduty.Member {
+	ids := map[string]bool{}
+	for _, member := range members.Members {
+		ids[member.ID] = true
+	}
+
+	result := []slackduty.Member{}
+	for _, member := range members.Removed {
+		if !ids[member.ID] {
+			result = append(result, member)
+		}
+	}
+
+	return result
+}
+
+func matchUser(member slackduty.Member, id, email string) bool {
+	return member.ID == id || (email != "" && strings.EqualFold(member.Email, email))
+}
+
+// userID returns the Slack ID and the email of the user.
+func userID(slackClient SlackClient, user string) (string, string, error) {
+	slackUser, err := slackClient.GetUser(user)
+	if err != nil {
+		return "", "", err
+	}
+
+	email := slackUser.Profile.Email
+	if s := strings.SplitN(user, ":", 2); len(s) == 2 && s[0] == "email" {
+		email = s[1]
+	}
+
+	return slackUser.ID, email, nil
+}
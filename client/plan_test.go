@@ -0,0 +1,152 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/KeisukeYamashita/slackduty/state"
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/slack-go/slack"
+)
+
+func newPlanTestClient() (*Client, *fakeSlackClient) {
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+	slackClient.users["email:carol@example.com"] = &slack.User{ID: "U5"}
+	slackClient.users["email:dave@example.com"] = &slack.User{ID: "U6"}
+	slackClient.usergroups["handle:oncall"] = []string{"U2", "U3"}
+
+	pdClient := newFakePagerdutyClient()
+	pdClient.schedules["name:web"] = []pagerduty.User{{Email: "alice@example.com"}}
+	pdClient.teams["name:web"] = []pagerduty.User{{Email: "alice@example.com"}, {Email: "bob@example.com"}, {Email: "carol@example.com"}, {Email: "dave@example.com"}}
+
+	store := &fakeStore{overrides: []state.Override{
+		{ID: "o1", Group: "test", Action: state.ActionAdd, User: "id:U4", Until: time.Now().Add(time.Hour)},
+		{ID: "o2", Group: "test", Action: state.ActionRemove, User: "email:dave@example.com", Until: time.Now().Add(time.Hour)},
+	}}
+	cfg := &config.Config{
+		Groups: []config.Group{
+			{
+				Name:       "test",
+				Usergroups: []string{"handle:oncall"},
+				Exclude:    []string{"email:carol@example.com"},
+				Members: &config.Members{
+					Pagerduty: config.Pagerduties{{Schedules: []config.Schedule{{Schedule: "name:web"}}, Teams: []string{"name:web"}}},
+				},
+			},
+		},
+	}
+
+	return &Client{config: cfg, pagerduty: pdClient, slack: slackClient, store: store, logger: log.NewDiscard()}, slackClient
+}
+
+func TestPlan(t *testing.T) {
+	c, slackClient := newPlanTestClient()
+
+	plans, err := c.Plan("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plans) != 1 {
+		t.Fatalf("plan count doesn't match got: %d want: 1", len(plans))
+	}
+
+	plan := plans[0]
+	if plan.Action != ActionUpdate {
+		t.Fatalf("action doesn't match got: %s want: %s", plan.Action, ActionUpdate)
+	}
+
+	if !reflect.DeepEqual(plan.Added, []string{"U1", "U4"}) && !reflect.DeepEqual(plan.Added, []string{"U4", "U1"}) {
+		t.Fatalf("added doesn't match got: %v", plan.Added)
+	}
+
+	if !reflect.DeepEqual(plan.Removed, []string{"U3"}) {
+		t.Fatalf("removed doesn't match got: %v want: [U3]", plan.Removed)
+	}
+
+	if got := slackClient.usergroups["handle:oncall"]; !reflect.DeepEqual(got, []string{"U2", "U3"}) {
+		t.Fatalf("plan should not update the usergroup got: %v", got)
+	}
+}
+
+func TestWhy(t *testing.T) {
+	c, _ := newPlanTestClient()
+
+	tcs := map[string]struct {
+		user      string
+		member    bool
+		current   bool
+		sources   []string
+		removedBy string
+	}{
+		"scheduled and team":  {"email:alice@example.com", true, false, []string{"pagerduty.schedule/name:web", "pagerduty.team/name:web"}, ""},
+		"override":            {"id:U4", true, false, []string{"override/o1"}, ""},
+		"will be removed":     {"id:U3", false, true, nil, ""},
+		"excluded":            {"email:carol@example.com", false, false, []string{"pagerduty.team/name:web"}, "exclude"},
+		"removed by override": {"id:U6", false, false, []string{"pagerduty.team/name:web"}, "override/o2"},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			reasons, err := c.Why("handle:oncall", tc.user)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			reason := reasons[0]
+			if reason.Member != tc.member || reason.Current != tc.current || reason.RemovedBy != tc.removedBy {
+				t.Fatalf("test %s reason doesn't match got: %+v", n, reason)
+			}
+
+			got := map[string]bool{}
+			for _, source := range reason.Sources {
+				got[source] = true
+			}
+
+			want := map[string]bool{}
+			for _, source := range tc.sources {
+				want[source] = true
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("test %s sources don't match got: %v want: %v", n, reason.Sources, tc.sources)
+			}
+		})
+	}
+}
+
+func TestPlan_Paused(t *testing.T) {
+	c, _ := newPlanTestClient()
+	store := c.store.(*fakeStore)
+	if err := store.Pause(state.Pause{Group: "test", RunID: "r1", Reason: "rollback", Timestamp: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := c.Plan("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := plans[0]
+	if plan.Action != ActionPaused || plan.Reason == "" {
+		t.Fatalf("plan should be paused got: %s reason: %s", plan.Action, plan.Reason)
+	}
+
+	if len(plan.Added) != 0 || len(plan.Removed) != 0 {
+		t.Fatalf("paused plan should not change the usergroup got added: %v removed: %v", plan.Added, plan.Removed)
+	}
+
+	reasons, err := c.Why("test", "email:alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reasons[0].Paused == "" {
+		t.Fatalf("reason should be paused got: %+v", reasons[0])
+	}
+}
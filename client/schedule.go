@@ -0,0 +1,289 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/PagerDuty/go-pagerduty"
+)
+
+const (
+	scheduleModeNext = "next"
+
+	scheduleLayerFinal     = "final"
+	scheduleLayerOverrides = "overrides"
+
+	// nextHorizon is how far the schedule is rendered to find the next
+	// handoff. It covers the monthly rotations.
+	nextHorizon = 31 * 24 * time.Hour
+)
+
+// validateSchedule validates the mode and the offset of the schedule.
+func validateSchedule(schedule config.Schedule) error {
+	switch schedule.Mode {
+	case "", scheduleModeNext:
+	default:
+		return fmt.Errorf("schedule mode %s is invalid, must be next schedule: %s", schedule.Mode, schedule.Schedule)
+	}
+
+	if schedule.At == "" {
+		return nil
+	}
+
+	if schedule.Mode != "" {
+		return fmt.Errorf("schedule mode and at can't be used together schedule: %s", schedule.Schedule)
+	}
+
+	if _, err := time.ParseDuration(schedule.At); err != nil {
+		return fmt.Errorf("schedule at %s is invalid, must be a duration(e.g. +24h) schedule: %s", schedule.At, schedule.Schedule)
+	}
+
+	return nil
+}
+
+// scheduleSelector returns the selector of the schedule with the layer and
+// the mode or the offset(e.g. `name:web-oncall#Primary@next`).
+func scheduleSelector(schedule config.Schedule) string {
+	selector := schedule.Schedule
+	if schedule.Layer != "" {
+		selector = fmt.Sprintf("%s#%s", selector, schedule.Layer)
+	}
+
+	switch {
+	case schedule.Mode != "":
+		return fmt.Sprintf("%s@%s", selector, schedule.Mode)
+	case schedule.At != "":
+		return fmt.Sprintf("%s@%s", selector, schedule.At)
+	default:
+		return selector
+	}
+}
+
+// getScheduledUsers returns the users of the schedule. The users on call at
+// the next handoff or at the offset are returned if the mode or the offset is
+// set. The layer is resolved at the execution time if neither is set.
+// Otherwise all users of the schedule are returned. The ends are when the
+// users leave the schedule by the email. Only the users on call now have them
+// if all users are returned.
+func (c *Client) getScheduledUsers(pdClient PagerdutyClient, schedule config.Schedule) ([]pagerduty.User, map[string]time.Time, error) {
+	if err := validateSchedule(schedule); err != nil {
+		return nil, nil, err
+	}
+
+	now := c.clock()
+	var shifts []shift
+	switch {
+	case schedule.Mode == scheduleModeNext:
+		pdSche, err := pdClient.RenderSchedule(schedule.Schedule, now, now.Add(nextHorizon))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entries, err := scheduleEntries(pdSche, schedule)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		handoff, ok, err := nextHandoff(entries, now)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ok {
+			return []pagerduty.User{}, nil, nil
+		}
+
+		shifts, err = onCallAt(entries, handoff)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Note: The users are the next on call until the handoff.
+		for i := range shifts {
+			shifts[i].End = handoff
+		}
+	case schedule.At != "" || schedule.Layer != "":
+		var d time.Duration
+		if schedule.At != "" {
+			d, _ = time.ParseDuration(schedule.At)
+		}
+		at := now.Add(d)
+
+		// Note: The schedule is rendered beyond the time to find the end
+		// of the shifts because the entries are cut at the until.
+		pdSche, err := pdClient.RenderSchedule(schedule.Schedule, at, at.Add(nextHorizon))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entries, err := scheduleEntries(pdSche, schedule)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		shifts, err = onCallAt(entries, at)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i := range shifts {
+			shifts[i].End = shifts[i].End.Add(-d)
+		}
+	default:
+		users, err := pdClient.GetScheduledUser(schedule.Schedule)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pdSche, err := pdClient.RenderSchedule(schedule.Schedule, now, now.Add(nextHorizon))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		shifts, err = onCallAt(pdSche.FinalSchedule.RenderedScheduleEntries, now)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ends := map[string]time.Time{}
+		for _, shift := range shifts {
+			for _, user := range users {
+				if user.ID == shift.ID {
+					ends[user.Email] = shift.End
+				}
+			}
+		}
+
+		return users, ends, nil
+	}
+
+	users := []pagerduty.User{}
+	ends := map[string]time.Time{}
+	for _, shift := range shifts {
+		user, err := pdClient.GetUser(fmt.Sprintf("id:%s", shift.ID))
+		if err != nil {
+			return nil, nil, err
+		}
+		users = append(users, *user)
+		ends[user.Email] = shift.End
+	}
+
+	return users, ends, nil
+}
+
+// scheduleEntries returns the rendered entries of the layer of the schedule.
+// The final schedule is used if the layer is not set.
+func scheduleEntries(pdSche *pagerduty.Schedule, schedule config.Schedule) ([]pagerduty.RenderedScheduleEntry, error) {
+	switch schedule.Layer {
+	case "", scheduleLayerFinal:
+		return pdSche.FinalSchedule.RenderedScheduleEntries, nil
+	case scheduleLayerOverrides:
+		return pdSche.OverrideSubschedule.RenderedScheduleEntries, nil
+	}
+
+	for _, layer := range pdSche.ScheduleLayers {
+		if layer.Name == schedule.Layer {
+			return layer.RenderedScheduleEntries, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no layer exists layer: %s schedule: %s", schedule.Layer, schedule.Schedule)
+}
+
+// shift is the user on call and the end of the shift.
+type shift struct {
+	ID  string
+	End time.Time
+}
+
+// onCallAt returns the shifts of the users whose entry covers the time. The
+// shift continues over the consecutive entries of the same user.
+func onCallAt(entries []pagerduty.RenderedScheduleEntry, t time.Time) ([]shift, error) {
+	type span struct {
+		start, end time.Time
+	}
+
+	spans := map[string][]span{}
+	for _, entry := range entries {
+		start, end, err := entryTime(entry)
+		if err != nil {
+			return nil, err
+		}
+		spans[entry.User.ID] = append(spans[entry.User.ID], span{start, end})
+	}
+
+	shifts := []shift{}
+	for id, userSpans := range spans {
+		var end time.Time
+		for _, s := range userSpans {
+			if !t.Before(s.start) && t.Before(s.end) && s.end.After(end) {
+				end = s.end
+			}
+		}
+
+		if end.IsZero() {
+			continue
+		}
+
+		for extended := true; extended; {
+			extended = false
+			for _, s := range userSpans {
+				if !s.start.After(end) && s.end.After(end) {
+					end = s.end
+					extended = true
+				}
+			}
+		}
+
+		shifts = append(shifts, shift{ID: id, End: end})
+	}
+
+	sort.Slice(shifts, func(i, j int) bool { return shifts[i].ID < shifts[j].ID })
+	return shifts, nil
+}
+
+// nextHandoff returns the time of the next handoff after the time. It is the
+// earliest end of the entries covering the time, or the earliest start of
+// the entries after the time if nobody is on call.
+func nextHandoff(entries []pagerduty.RenderedScheduleEntry, t time.Time) (time.Time, bool, error) {
+	var current, upcoming time.Time
+	for _, entry := range entries {
+		start, end, err := entryTime(entry)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+
+		switch {
+		case !t.Before(start) && t.Before(end):
+			if current.IsZero() || end.Before(current) {
+				current = end
+			}
+		case start.After(t):
+			if upcoming.IsZero() || start.Before(upcoming) {
+				upcoming = start
+			}
+		}
+	}
+
+	if !current.IsZero() {
+		return current, true, nil
+	}
+
+	return upcoming, !upcoming.IsZero(), nil
+}
+
+func entryTime(entry pagerduty.RenderedScheduleEntry) (time.Time, time.Time, error) {
+	start, err := time.Parse(time.RFC3339, entry.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse the start of the schedule entry start: %s error: %v", entry.Start, err)
+	}
+
+	end, err := time.Parse(time.RFC3339, entry.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse the end of the schedule entry end: %s error: %v", entry.End, err)
+	}
+
+	return start, end, nil
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/slack-go/slack"
+)
+
+func scheduleEntry(user, start, end string) pagerduty.RenderedScheduleEntry {
+	return pagerduty.RenderedScheduleEntry{Start: start, End: end, User: pagerduty.APIObject{ID: user}}
+}
+
+func TestNextHandoff(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tcs := map[string]struct {
+		entries []pagerduty.RenderedScheduleEntry
+		want    time.Time
+		ok      bool
+	}{
+		"handoff": {
+			[]pagerduty.RenderedScheduleEntry{
+				scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-02T09:00:00Z"),
+				scheduleEntry("P2", "2020-06-02T09:00:00Z", "2020-06-03T09:00:00Z"),
+			},
+			time.Date(2020, 6, 2, 9, 0, 0, 0, time.UTC), true,
+		},
+		"earliest handoff of the overlapping entries": {
+			[]pagerduty.RenderedScheduleEntry{
+				scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-02T09:00:00Z"),
+				scheduleEntry("P3", "2020-06-01T10:00:00Z", "2020-06-01T18:00:00Z"),
+			},
+			time.Date(2020, 6, 1, 18, 0, 0, 0, time.UTC), true,
+		},
+		"nobody is on call": {
+			[]pagerduty.RenderedScheduleEntry{
+				scheduleEntry("P2", "2020-06-03T09:00:00Z", "2020-06-04T09:00:00Z"),
+				scheduleEntry("P1", "2020-06-02T09:00:00Z", "2020-06-03T09:00:00Z"),
+			},
+			time.Date(2020, 6, 2, 9, 0, 0, 0, time.UTC), true,
+		},
+		"no entries": {nil, time.Time{}, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, ok, err := nextHandoff(tc.entries, now)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			if ok != tc.ok || !got.Equal(tc.want) {
+				t.Fatalf("handoff doesn't match got: %v(%v) want: %v(%v)", got, ok, tc.want, tc.ok)
+			}
+		})
+	}
+}
+
+func TestOnCallAt(t *testing.T) {
+	entries := []pagerduty.RenderedScheduleEntry{
+		scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-02T09:00:00Z"),
+		scheduleEntry("P1", "2020-06-02T09:00:00Z", "2020-06-03T09:00:00Z"),
+		scheduleEntry("P2", "2020-06-01T10:00:00Z", "2020-06-01T18:00:00Z"),
+		scheduleEntry("P3", "2020-06-02T09:00:00Z", "2020-06-03T09:00:00Z"),
+	}
+
+	got, err := onCallAt(entries, time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	want := []shift{
+		{ID: "P1", End: time.Date(2020, 6, 3, 9, 0, 0, 0, time.UTC)},
+		{ID: "P2", End: time.Date(2020, 6, 1, 18, 0, 0, 0, time.UTC)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("shifts don't match got: %v want: %v", got, want)
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	tcs := map[string]struct {
+		schedule config.Schedule
+		success  bool
+	}{
+		"selector only":    {config.Schedule{Schedule: "name:web"}, true},
+		"next":             {config.Schedule{Schedule: "name:web", Mode: "next"}, true},
+		"at":               {config.Schedule{Schedule: "name:web", At: "+24h"}, true},
+		"invalid mode":     {config.Schedule{Schedule: "name:web", Mode: "previous"}, false},
+		"invalid at":       {config.Schedule{Schedule: "name:web", At: "tomorrow"}, false},
+		"both mode and at": {config.Schedule{Schedule: "name:web", Mode: "next", At: "+24h"}, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if err := validateSchedule(tc.schedule); (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+		})
+	}
+}
+
+func TestGetMembers_ScheduleModes(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+	slackClient.users["email:carol@example.com"] = &slack.User{ID: "U3"}
+
+	pdClient := newFakePagerdutyClient()
+	pdClient.users["id:P1"] = &pagerduty.User{Email: "alice@example.com"}
+	pdClient.users["id:P2"] = &pagerduty.User{Email: "bob@example.com"}
+	pdClient.users["id:P3"] = &pagerduty.User{Email: "carol@example.com"}
+	pdClient.schedules["name:web"] = []pagerduty.User{{Email: "alice@example.com"}, {Email: "bob@example.com"}, {Email: "carol@example.com"}}
+	pdClient.rendered["name:web"] = &pagerduty.Schedule{
+		FinalSchedule: pagerduty.ScheduleLayer{
+			RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{
+				scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-02T09:00:00Z"),
+				scheduleEntry("P2", "2020-06-02T09:00:00Z", "2020-06-03T09:00:00Z"),
+				scheduleEntry("P3", "2020-06-03T09:00:00Z", "2020-06-04T09:00:00Z"),
+			},
+		},
+	}
+
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := &Client{pagerduty: pdClient, slack: slackClient, now: func() time.Time { return now }, logger: log.NewDiscard()}
+
+	tcs := map[string]struct {
+		schedule config.Schedule
+		want     []string
+	}{
+		"all users": {config.Schedule{Schedule: "name:web"}, []string{"U1", "U2", "U3"}},
+		"next":      {config.Schedule{Schedule: "name:web", Mode: "next"}, []string{"U2"}},
+		"at +0h":    {config.Schedule{Schedule: "name:web", At: "+0h"}, []string{"U1"}},
+		"at +48h":   {config.Schedule{Schedule: "name:web", At: "+48h"}, []string{"U3"}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			cfg := &config.Members{Pagerduty: config.Pagerduties{{Schedules: []config.Schedule{tc.schedule}}}}
+			members, err := c.GetMembers(slackClient, cfg)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetMembers_ScheduleLayers(t *testing.T) {
+	slackClient := newFakeSlackClient()
+	slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+	slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+	slackClient.users["email:carol@example.com"] = &slack.User{ID: "U3"}
+
+	pdClient := newFakePagerdutyClient()
+	pdClient.users["id:P1"] = &pagerduty.User{Email: "alice@example.com"}
+	pdClient.users["id:P2"] = &pagerduty.User{Email: "bob@example.com"}
+	pdClient.users["id:P3"] = &pagerduty.User{Email: "carol@example.com"}
+	pdClient.rendered["name:web"] = &pagerduty.Schedule{
+		ScheduleLayers: []pagerduty.ScheduleLayer{
+			{Name: "Primary", RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-02T09:00:00Z")}},
+			{Name: "Shadow", RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P2", "2020-06-01T09:00:00Z", "2020-06-02T09:00:00Z")}},
+		},
+		OverrideSubschedule: pagerduty.ScheduleLayer{
+			RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P3", "2020-06-01T10:00:00Z", "2020-06-01T14:00:00Z")},
+		},
+		FinalSchedule: pagerduty.ScheduleLayer{
+			RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{
+				scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-01T10:00:00Z"),
+				scheduleEntry("P3", "2020-06-01T10:00:00Z", "2020-06-01T14:00:00Z"),
+				scheduleEntry("P1", "2020-06-01T14:00:00Z", "2020-06-02T09:00:00Z"),
+			},
+		},
+	}
+
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := &Client{pagerduty: pdClient, slack: slackClient, now: func() time.Time { return now }, logger: log.NewDiscard()}
+
+	tcs := map[string]struct {
+		schedule config.Schedule
+		want     []string
+		success  bool
+	}{
+		"final":         {config.Schedule{Schedule: "name:web", Layer: "final"}, []string{"U3"}, true},
+		"layer":         {config.Schedule{Schedule: "name:web", Layer: "Shadow"}, []string{"U2"}, true},
+		"overrides":     {config.Schedule{Schedule: "name:web", Layer: "overrides"}, []string{"U3"}, true},
+		"final next":    {config.Schedule{Schedule: "name:web", Layer: "final", Mode: "next"}, []string{"U1"}, true},
+		"no overrides":  {config.Schedule{Schedule: "name:web", Layer: "overrides", At: "+4h"}, []string{}, true},
+		"unknown layer": {config.Schedule{Schedule: "name:web", Layer: "Secondary"}, nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			cfg := &config.Members{Pagerduty: config.Pagerduties{{Schedules: []config.Schedule{tc.schedule}}}}
+			members, err := c.GetMembers(slackClient, cfg)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if !tc.success {
+				return
+			}
+
+			got := []string{}
+			for _, member := range members.Members {
+				got = append(got, member.ID)
+			}
+			sort.Strings(got)
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members doesn't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -2,39 +2,190 @@ package client
 
 import (
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/KeisukeYamashita/slackduty/slackduty"
 	"github.com/slack-go/slack"
 )
 
+// userInfoConcurrency is the maximum number of the users.info calls at the
+// same time. The profiles of all members of a large channel are fetched, so
+// the calls are bounded not to hit the rate limit.
+const userInfoConcurrency = 4
+
+// userCacheTTL is how long the fetched users are reused. The sources and the
+// exclude_accounts look up the same users in a sync.
+const userCacheTTL = time.Minute
+
 // SlackClient is a interface that the Slack client should implement
 type SlackClient interface {
 	CreateUsergroup() error
+	DisableUsergroup(string) error
+	EnableUsergroup(string) error
+	GetChannelMembers(string) ([]string, error)
 	GetUser(string) (*slack.User, error)
+	GetUserInfo(string) (*slack.User, error)
+	GetUsergroupMembers(string) ([]string, error)
 	GetUsergroups() ([]slack.UserGroup, error)
+	PostMessage(string, string) error
+	TeamID() (string, error)
 	UpdateUsergroup(string, string) error
 }
 
 var _ SlackClient = (*slackClient)(nil)
 
 type slackClient struct {
-	client *slack.Client
+	mux     sync.RWMutex
+	client  *slack.Client
+	options []slack.Option
+	teamID  string
+	users   chan struct{}
+
+	cacheMux sync.Mutex
+	cache    map[string]cachedUser
+}
+
+type cachedUser struct {
+	user    *slack.User
+	expires time.Time
+}
+
+type slackOptions struct {
+	teamID string
+}
+
+// SlackOption configures the Slack client
+type SlackOption func(*slackOptions)
+
+// WithTeamID scopes the usergroup API calls to the workspace of the team ID.
+// It is required to manage workspace-scoped usergroups by an Enterprise Grid
+// organization token.
+func WithTeamID(teamID string) SlackOption {
+	return func(o *slackOptions) {
+		o.teamID = teamID
+	}
 }
 
 // NewSlackClient creates a new Slack API client
-func NewSlackClient(apiKey string) SlackClient {
-	client := slack.New(apiKey)
+func NewSlackClient(apiKey string, opts ...SlackOption) SlackClient {
+	var o slackOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	slackOpts := []slack.Option{}
+	if o.teamID != "" {
+		slackOpts = append(slackOpts, slack.OptionHTTPClient(&teamHTTPClient{client: &http.Client{}, teamID: o.teamID}))
+	}
+
+	client := slack.New(apiKey, slackOpts...)
 
 	return &slackClient{
-		client: client,
+		client:  client,
+		options: slackOpts,
+		teamID:  o.teamID,
+		users:   make(chan struct{}, userInfoConcurrency),
 	}
 }
 
+// SetAPIKey replaces the API key of the client. It is used when the API key
+// is rotated while Slackduty is running.
+func (c *slackClient) SetAPIKey(apiKey string) {
+	c.mux.Lock()
+	c.client = slack.New(apiKey, c.options...)
+	c.mux.Unlock()
+}
+
+func (c *slackClient) api() *slack.Client {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.client
+}
+
 func (c *slackClient) CreateUsergroup() error {
 	return nil
 }
 
+func (c *slackClient) DisableUsergroup(handle string) error {
+	groupID, err := c.getUsergroupID(handle)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.api().DisableUserGroup(groupID)
+	return err
+}
+
+func (c *slackClient) EnableUsergroup(handle string) error {
+	groupID, err := c.getUsergroupID(handle)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.api().EnableUserGroup(groupID)
+	return err
+}
+
+// GetChannelMembers returns the Slack IDs of the members of the channel. The
+// channel is the name with `#`(e.g. `#web-team`) or the channel ID.
+func (c *slackClient) GetChannelMembers(channel string) ([]string, error) {
+	channelID := channel
+	if strings.HasPrefix(channel, "#") {
+		var err error
+		channelID, err = c.getChannelID(strings.TrimPrefix(channel, "#"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	members := []string{}
+	params := &slack.GetUsersInConversationParameters{ChannelID: channelID, Limit: 1000}
+	for {
+		ids, cursor, err := c.api().GetUsersInConversation(params)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, ids...)
+		if cursor == "" {
+			return members, nil
+		}
+		params.Cursor = cursor
+	}
+}
+
+func (c *slackClient) getChannelID(name string) (string, error) {
+	params := &slack.GetConversationsParameters{
+		ExcludeArchived: "true",
+		Limit:           1000,
+		Types:           []string{"public_channel", "private_channel"},
+	}
+
+	for {
+		channels, cursor, err := c.api().GetConversations(params)
+		if err != nil {
+			return "", err
+		}
+
+		for _, channel := range channels {
+			if channel.Name == name {
+				return channel.ID, nil
+			}
+		}
+
+		if cursor == "" {
+			return "", fmt.Errorf("channel doesn't exists for name: #%s", name)
+		}
+		params.Cursor = cursor
+	}
+}
+
 func (c *slackClient) GetUser(user string) (*slack.User, error) {
 	s := strings.Split(user, ":")
 	if len(s) != 2 {
@@ -46,67 +197,179 @@ func (c *slackClient) GetUser(user string) (*slack.User, error) {
 
 	switch kind {
 	case "id":
-		// Note(KeisukeYamashita): To update Slack usergroup, the user ID is only required.
-		// Therefore, we don't need to access the Slack API for future info and I early return with the slack.User struct
-		return &slack.User{ID: val}, nil
+		return c.GetUserInfo(val)
 	case "email":
-		slackUser, err := c.client.GetUserByEmail(val)
+		slackUser, err := c.api().GetUserByEmail(val)
 		if err != nil {
 			return nil, err
 		}
 
+		c.cacheUser(slackUser)
 		return slackUser, nil
 	default:
 		return nil, fmt.Errorf("user kind %s is invalid, must be email, id for user: %s", kind, user)
 	}
 }
 
+// GetUserInfo returns the user with the account attributes by the Slack ID.
+// It waits for the retry after of the API if it is rate limited. The user is
+// reused for a while once it is fetched.
+func (c *slackClient) GetUserInfo(id string) (*slack.User, error) {
+	if user, ok := c.cachedUser(id); ok {
+		return user, nil
+	}
+
+	c.users <- struct{}{}
+	defer func() { <-c.users }()
+
+	for {
+		user, err := c.api().GetUserInfo(id)
+		if rateLimited, ok := err.(*slack.RateLimitedError); ok {
+			time.Sleep(rateLimited.RetryAfter)
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		c.cacheUser(user)
+		return user, nil
+	}
+}
+
+func (c *slackClient) cachedUser(id string) (*slack.User, bool) {
+	c.cacheMux.Lock()
+	defer c.cacheMux.Unlock()
+	cached, ok := c.cache[id]
+	if !ok || time.Now().After(cached.expires) {
+		return nil, false
+	}
+
+	return cached.user, true
+}
+
+func (c *slackClient) cacheUser(user *slack.User) {
+	c.cacheMux.Lock()
+	defer c.cacheMux.Unlock()
+	if c.cache == nil {
+		c.cache = map[string]cachedUser{}
+	}
+	c.cache[user.ID] = cachedUser{user: user, expires: time.Now().Add(userCacheTTL)}
+}
+
+// TeamID returns the team ID of the workspace. It is the configured one or
+// the team of the API key.
+func (c *slackClient) TeamID() (string, error) {
+	c.mux.RLock()
+	teamID := c.teamID
+	c.mux.RUnlock()
+	if teamID != "" {
+		return teamID, nil
+	}
+
+	resp, err := c.api().AuthTest()
+	if err != nil {
+		return "", err
+	}
+
+	c.mux.Lock()
+	c.teamID = resp.TeamID
+	c.mux.Unlock()
+	return resp.TeamID, nil
+}
+
+// GetUsergroups returns the usergroups including the disabled ones.
 func (c *slackClient) GetUsergroups() ([]slack.UserGroup, error) {
-	return c.client.GetUserGroups()
+	return c.api().GetUserGroups(slack.GetUserGroupsOptionIncludeDisabled(true))
+}
+
+func (c *slackClient) GetUsergroupMembers(handle string) ([]string, error) {
+	groupID, err := c.getUsergroupID(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.api().GetUserGroupMembers(groupID)
+}
+
+// PostMessage posts a plain text message to the channel. Passing a user ID
+// as the channel sends a direct message to the user from the app.
+func (c *slackClient) PostMessage(channel, text string) error {
+	_, _, err := c.api().PostMessage(channel, slack.MsgOptionText(text, false))
+	return err
 }
 
 func (c *slackClient) UpdateUsergroup(handle string, members string) error {
+	groupID, err := c.getUsergroupID(handle)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.api().UpdateUserGroupMembers(groupID, members)
+	return err
+}
+
+func (c *slackClient) getUsergroupID(handle string) (string, error) {
 	s := strings.Split(handle, ":")
 	if len(s) != 2 {
-		return fmt.Errorf("handle is specified in wrong format handle: %s", handle)
+		return "", fmt.Errorf("handle is specified in wrong format handle: %s", handle)
 	}
 
 	kind := s[0]
 	val := s[1]
 
-	var groupID string
 	switch kind {
 	case "id":
-		groupID = val
+		return val, nil
 	case "handle":
-		ugs, err := c.client.GetUserGroups()
+		ugs, err := c.GetUsergroups()
 		if err != nil {
-			return err
+			return "", err
 		}
 
-		var exists bool
-		var usergroup *slack.UserGroup
-
-	LOOP:
 		for _, ug := range ugs {
 			if ug.Handle == val {
-				exists = true
-				usergroup = &ug
-				break LOOP
+				return ug.ID, nil
 			}
 		}
 
-		if !exists {
-			return fmt.Errorf("usergroup doesn't exists for handle: %s", handle)
-		}
-
-		groupID = usergroup.ID
+		return "", fmt.Errorf("usergroup doesn't exists for handle: %s", handle)
 	default:
-		return fmt.Errorf("handle kind %s is invalid, must be id for name: %s", kind, handle)
+		return "", fmt.Errorf("handle kind %s is invalid, must be id for name: %s", kind, handle)
 	}
+}
 
-	_, err := c.client.UpdateUserGroupMembers(groupID, members)
-	return err
+// teamHTTPClient adds the team_id parameter to the usergroup API calls and
+// the channel list.
+// github.com/slack-go/slack doesn't support the team_id for usergroups yet.
+type teamHTTPClient struct {
+	client *http.Client
+	teamID string
+}
+
+func (c *teamHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	if req.Method != http.MethodPost || !(strings.HasPrefix(method, "usergroups.") || method == "conversations.list") || req.Body == nil {
+		return c.client.Do(req)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	values.Set("team_id", c.teamID)
+
+	encoded := values.Encode()
+	req.Body = ioutil.NopCloser(strings.NewReader(encoded))
+	req.ContentLength = int64(len(encoded))
+	return c.client.Do(req)
 }
 
 func convSlackUser(user *slack.User, email string) *slackduty.Member {
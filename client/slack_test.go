@@ -0,0 +1,212 @@
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestTeamHTTPClient(t *testing.T) {
+	tcs := map[string]struct {
+		method string
+		want   string
+	}{
+		"usergroup method": {"usergroups.users.update", "T0123"},
+		"channel list":     {"conversations.list", "T0123"},
+		"other method":     {"users.lookupByEmail", ""},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			var got url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				got, _ = url.ParseQuery(string(body))
+			}))
+			defer server.Close()
+
+			client := &teamHTTPClient{client: server.Client(), teamID: "T0123"}
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/api/"+tc.method, strings.NewReader(url.Values{"usergroup": {"S0123"}}.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.Do(req); err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			if teamID := got.Get("team_id"); teamID != tc.want {
+				t.Fatalf("team_id doesn't match got: %s want: %s", teamID, tc.want)
+			}
+
+			if usergroup := got.Get("usergroup"); usergroup != "S0123" {
+				t.Fatalf("original parameter is lost got: %s", usergroup)
+			}
+		})
+	}
+}
+
+func TestSlackClient_GetChannelMembers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch path := r.URL.Path; {
+		case strings.HasSuffix(path, "conversations.list"):
+			if r.Form.Get("cursor") == "" {
+				fmt.Fprint(w, `{"ok":true,"channels":[{"id":"C1","name":"general"}],"response_metadata":{"next_cursor":"next"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"ok":true,"channels":[{"id":"C2","name":"web-team"}]}`)
+		case strings.HasSuffix(path, "conversations.members"):
+			if r.Form.Get("channel") != "C2" {
+				fmt.Fprint(w, `{"ok":false,"error":"channel_not_found"}`)
+				return
+			}
+			if r.Form.Get("cursor") == "" {
+				fmt.Fprint(w, `{"ok":true,"members":["U1"],"response_metadata":{"next_cursor":"next"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"ok":true,"members":["U2"]}`)
+		default:
+			fmt.Fprint(w, `{"ok":false,"error":"unknown_method"}`)
+		}
+	}))
+	defer server.Close()
+
+	c := &slackClient{client: slack.New("test", slack.OptionAPIURL(server.URL+"/"))}
+
+	tcs := map[string]struct {
+		channel string
+		want    []string
+		success bool
+	}{
+		"name":    {"#web-team", []string{"U1", "U2"}, true},
+		"id":      {"C2", []string{"U1", "U2"}, true},
+		"unknown": {"#unknown", nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := c.GetChannelMembers(tc.channel)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if tc.success && !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members don't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSlackClient_GetUserInfo(t *testing.T) {
+	var mux sync.Mutex
+	var inflight, max int
+	var limited sync.Once
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.Lock()
+		inflight++
+		if inflight > max {
+			max = inflight
+		}
+		mux.Unlock()
+		defer func() {
+			mux.Lock()
+			inflight--
+			mux.Unlock()
+		}()
+
+		rateLimited := false
+		limited.Do(func() { rateLimited = true })
+		if rateLimited {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"ok":true,"user":{"id":"%s"}}`, r.Form.Get("user"))
+	}))
+	defer server.Close()
+
+	c := &slackClient{client: slack.New("test", slack.OptionAPIURL(server.URL+"/")), users: make(chan struct{}, userInfoConcurrency)}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		id := fmt.Sprintf("U%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := c.GetUserInfo(id)
+			if err == nil && user.ID != id {
+				err = fmt.Errorf("user doesn't match got: %s want: %s", user.ID, id)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if max > userInfoConcurrency {
+		t.Fatalf("too many concurrent calls got: %d want: <= %d", max, userInfoConcurrency)
+	}
+}
+
+func TestSlackClient_GetUser(t *testing.T) {
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch path := r.URL.Path; {
+		case strings.HasSuffix(path, "users.info"):
+			calls[r.Form.Get("user")]++
+			fmt.Fprintf(w, `{"ok":true,"user":{"id":"%s","deleted":true,"profile":{"email":"alice@example.com"}}}`, r.Form.Get("user"))
+		case strings.HasSuffix(path, "users.lookupByEmail"):
+			fmt.Fprint(w, `{"ok":true,"user":{"id":"U2","is_bot":true}}`)
+		default:
+			fmt.Fprint(w, `{"ok":false,"error":"unknown_method"}`)
+		}
+	}))
+	defer server.Close()
+
+	c := &slackClient{client: slack.New("test", slack.OptionAPIURL(server.URL+"/")), users: make(chan struct{}, userInfoConcurrency)}
+
+	user, err := c.GetUser("id:U1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !user.Deleted || user.Profile.Email != "alice@example.com" {
+		t.Fatalf("user doesn't have the profile got: %+v", user)
+	}
+
+	if _, err := c.GetUser("email:bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"U1", "U2"} {
+		if _, err := c.GetUserInfo(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := map[string]int{"U1": 1}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("users.info calls don't match got: %v want: %v", calls, want)
+	}
+}
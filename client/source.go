@@ -0,0 +1,126 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"github.com/KeisukeYamashita/slackduty/state"
+)
+
+// Source is an on-call service account that resolves the members of the
+// selectors. PagerDuty and Opsgenie implement it.
+type Source interface {
+	// Name returns the name of the service and the account for the logs.
+	Name() string
+	// Members adds the members resolved by the selectors to the members.
+	Members(SlackClient, *slackduty.Members) error
+}
+
+var (
+	_ Source = (*pagerdutyMembers)(nil)
+	_ Source = (*opsgenieMembers)(nil)
+)
+
+// pagerdutyMembers is the PagerDuty members of an account.
+type pagerdutyMembers struct {
+	client   *Client
+	pdClient PagerdutyClient
+	config   *config.Pagerduty
+}
+
+func (s *pagerdutyMembers) Name() string {
+	return accountName("pagerduty", s.config.Account)
+}
+
+func (s *pagerdutyMembers) Members(slackClient SlackClient, members *slackduty.Members) error {
+	return s.client.getPagerDutyMembers(slackClient, s.pdClient, s.config, members)
+}
+
+// opsgenieMembers is the Opsgenie members of an account.
+type opsgenieMembers struct {
+	client   *Client
+	ogClient OpsgenieClient
+	config   *config.Opsgenie
+}
+
+func (s *opsgenieMembers) Name() string {
+	return accountName("opsgenie", s.config.Account)
+}
+
+func (s *opsgenieMembers) Members(slackClient SlackClient, members *slackduty.Members) error {
+	return s.client.getOpsgenieMembers(slackClient, s.ogClient, s.config, members)
+}
+
+// sources returns the on-call service sources of the members config. It
+// fails if the config refers an account that is not configured.
+func (c *Client) sources(cfg *config.Members) ([]Source, error) {
+	sources := []Source{}
+	for _, pdConfig := range cfg.Pagerduty {
+		pdClient, err := c.pagerdutyClient(pdConfig.Account)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, &pagerdutyMembers{client: c, pdClient: pdClient, config: pdConfig})
+	}
+
+	for _, ogConfig := range cfg.Opsgenie {
+		ogClient, err := c.opsgenieClient(ogConfig.Account)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, &opsgenieMembers{client: c, ogClient: ogClient, config: ogConfig})
+	}
+
+	return sources, nil
+}
+
+// accountName returns the name of the service with the account if it is not
+// the default one(e.g. `pagerduty[legacy]`).
+func accountName(service, account string) string {
+	if account == "" || account == defaultAccount {
+		return service
+	}
+
+	return fmt.Sprintf("%s[%s]", service, account)
+}
+
+// pagerdutySource returns the label of the PagerDuty selector that a member is
+// resolved from(e.g. `pagerduty.schedule/name:web-oncall`). The account is
+// included if it is not the default one.
+func pagerdutySource(account, kind, selector string) string {
+	return fmt.Sprintf("%s.%s/%s", accountName("pagerduty", account), kind, selector)
+}
+
+// calendarSource returns the label of the calendar that a member is resolved
+// from(e.g. `calendar/calendars/release-captain.ics`).
+func calendarSource(path string) string {
+	return fmt.Sprintf("calendar/%s", path)
+}
+
+// fileSource returns the label of the roster file that a member is resolved
+// from(e.g. `file/rosters/release-captain.csv`).
+func fileSource(path string) string {
+	return fmt.Sprintf("file/%s", path)
+}
+
+// opsgenieSource returns the label of the Opsgenie selector that a member is
+// resolved from(e.g. `opsgenie.schedule/name:web-oncall`). The account is
+// included if it is not the default one.
+func opsgenieSource(account, kind, selector string) string {
+	return fmt.Sprintf("%s.%s/%s", accountName("opsgenie", account), kind, selector)
+}
+
+// slackSource returns the label of the Slack selector that a member is
+// resolved from(e.g. `slack/email:manager@example.com`).
+func slackSource(selector string) string {
+	return fmt.Sprintf("slack/%s", selector)
+}
+
+// overrideSource returns the label of the override that a member is added
+// by(e.g. `override/1a2b3c4d`).
+func overrideSource(override state.Override) string {
+	return fmt.Sprintf("override/%s", override.ID)
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"github.com/KeisukeYamashita/slackduty/state"
+	"go.uber.org/zap"
+)
+
+// record saves the result of synchronizing the usergroup to the state store.
+// Failing to save doesn't fail the sync.
+func (c *Client) record(group *config.Group, workspace, usergroup, runID string, outcome state.Outcome, members *slackduty.Members, syncErr error) {
+	if c.store == nil {
+		return
+	}
+
+	record := state.Record{
+		RunID:     runID,
+		Group:     group.Key(),
+		Workspace: workspace,
+		Usergroup: usergroup,
+		Timestamp: c.clock(),
+		Outcome:   outcome,
+	}
+
+	if members != nil {
+		record.Members = members.Members
+		record.Breakdown = members.Breakdown
+	}
+
+	if syncErr != nil {
+		record.Error = syncErr.Error()
+	}
+
+	if err := c.store.Save(record); err != nil {
+		c.logger.Warn("failed to save the state", zap.Error(err), zap.String("group", record.Group), zap.String("usergroup", usergroup), zap.String("workspace", workspace))
+	}
+}
+
+// currentMembers returns the Slack IDs currently in the usergroup. If the
+// Slack API fails, it falls back to the members applied by the last
+// successful sync in the state store.
+func (c *Client) currentMembers(slackClient SlackClient, group *config.Group, workspace, usergroup string) ([]string, error) {
+	current, err := slackClient.GetUsergroupMembers(usergroup)
+	if err == nil || c.store == nil {
+		return current, err
+	}
+
+	last, lastErr := state.Last(c.store, group.Key(), workspace, usergroup)
+	if lastErr != nil || last == nil {
+		return nil, err
+	}
+
+	c.logger.Warn("failed to get the current members of the Slack usergroup, use the last applied members", zap.Error(err), zap.String("group", group.Name), zap.String("usergroup", usergroup), zap.String("run id", last.RunID))
+	ids := []string{}
+	for _, member := range last.Members {
+		ids = append(ids, member.ID)
+	}
+
+	return ids, nil
+}
+
+// paused returns the pause of the group if the automatic sync of the group is
+// paused in the state store.
+func (c *Client) paused(group *config.Group) (*state.Pause, error) {
+	if c.store == nil {
+		return nil, nil
+	}
+
+	return c.store.Paused(group.Key())
+}
+
+// applyOverrides adds or removes the users of the active overrides of the
+// group to the members. It is applied after the exclude so that the override
+// can add a excluded user.
+func (c *Client) applyOverrides(slackClient SlackClient, group *config.Group, members *slackduty.Members) error {
+	if c.store == nil {
+		return nil
+	}
+
+	overrides, err := c.store.Overrides(group.Key())
+	if err != nil {
+		return err
+	}
+
+	for _, override := range state.Active(overrides, c.clock()) {
+		slackUser, err := slackClient.GetUser(override.User)
+		if err != nil {
+			return fmt.Errorf("failed to get the user of the override id: %s error: %v", override.ID, err)
+		}
+
+		switch override.Action {
+		case state.ActionAdd:
+			member := convSlackUser(slackUser, slackUser.Profile.Email)
+			member.Until = override.Until
+			members.AddFrom(overrideSource(override), member)
+		case state.ActionRemove:
+			members.RemoveBy(slackUser.ID, overrideSource(override))
+		default:
+			return fmt.Errorf("override action is invalid, must be add or remove id: %s", override.ID)
+		}
+
+		c.logger.Info("applied the override", zap.String("group", group.Name), zap.String("id", override.ID), zap.String("action", string(override.Action)), zap.String("user", override.User), zap.Time("until", override.Until))
+	}
+
+	return nil
+}
+
+// Rollback restores the usergroups of the group to the members of the
+// records and pauses the automatic sync of the group until it is resumed.
+func (c *Client) Rollback(key string, records []state.Record) (string, error) {
+	if c.store == nil {
+		return "", errors.New("state is not configured")
+	}
+
+	group, err := c.config.FindGroup(key)
+	if err != nil {
+		return "", err
+	}
+
+	// The group is paused before the usergroups are restored so that the
+	// scheduled sync doesn't overwrite them. The pause is kept even if the
+	// rollback fails halfway.
+	runID := state.NewRunID(c.clock())
+	pause := state.Pause{
+		Group:     group.Key(),
+		RunID:     runID,
+		Reason:    "rollback",
+		Timestamp: c.clock(),
+	}
+
+	if err := c.store.Pause(pause); err != nil {
+		return "", err
+	}
+
+	for _, record := range records {
+		slackClient, err := c.slackClient(record.Workspace)
+		if err != nil {
+			return "", err
+		}
+
+		var current []string
+		if group.Notify != nil {
+			current, err = c.currentMembers(slackClient, group, record.Workspace, record.Usergroup)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		if err := c.updateUsergroup(slackClient, record.Usergroup, record.Members); err != nil {
+			c.record(group, record.Workspace, record.Usergroup, runID, state.OutcomeFailed, nil, err)
+			return "", err
+		}
+
+		if group.Notify != nil {
+			added, removed := slackduty.Diff(current, record.Members)
+			c.notifyMembers(slackClient, group, record.Usergroup, nil, added, removed)
+		}
+
+		c.record(group, record.Workspace, record.Usergroup, runID, state.OutcomeRolledBack, &slackduty.Members{Members: record.Members, Breakdown: record.Breakdown}, nil)
+		c.logger.Info("rolled back a slack usergroup", zap.String("group", group.Name), zap.String("usergroup", record.Usergroup), zap.String("workspace", record.Workspace), zap.String("run id", record.RunID))
+	}
+
+	return runID, nil
+}
+
+// Resume resumes the automatic sync of the group paused by the rollback.
+func (c *Client) Resume(key string) error {
+	if c.store == nil {
+		return errors.New("state is not configured")
+	}
+
+	group, err := c.config.FindGroup(key)
+	if err != nil {
+		return err
+	}
+
+	return c.store.Resume(group.Key())
+}
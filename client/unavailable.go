@@ -0,0 +1,178 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/roster"
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+	"github.com/PagerDuty/go-pagerduty"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// excludeUnavailable removes the members who are unavailable at the execution
+// time by the exclude_unavailable of the group.
+func (c *Client) excludeUnavailable(slackClient SlackClient, group *config.Group, members *slackduty.Members) error {
+	unavailable := group.ExcludeUnavailable
+	if unavailable == nil {
+		return nil
+	}
+
+	pdClient, err := c.pagerdutyClient(unavailable.Account)
+	if err != nil {
+		return err
+	}
+
+	now := c.clock()
+	eg := errgroup.Group{}
+	var mux sync.Mutex
+	excluded := map[string]string{}
+	exclude := func(user, reason string) {
+		slackUser, err := slackClient.GetUser(user)
+		if err != nil {
+			// Note: The time-off calendars can have the people who are not
+			// in the workspace(e.g. the guests of the events).
+			c.logger.Warn("failed to get the unavailable user", zap.Error(err), zap.String("group", group.Name), zap.String("user", user))
+			return
+		}
+
+		mux.Lock()
+		excluded[slackUser.ID] = reason
+		mux.Unlock()
+	}
+
+	for _, schedule := range unavailable.Schedules {
+		schedule := schedule
+		eg.Go(func() error {
+			pdUsers, err := c.overriddenUsers(pdClient, schedule, now)
+			if err != nil {
+				return err
+			}
+
+			for _, pdUser := range pdUsers {
+				exclude(fmt.Sprintf("email:%s", pdUser.Email), pagerdutySource(unavailable.Account, "override", schedule))
+			}
+
+			return nil
+		})
+	}
+
+	for _, cal := range unavailable.Calendars {
+		cal := cal
+		eg.Go(func() error {
+			emails, err := calendarEmails(cal, now)
+			if err != nil {
+				return err
+			}
+
+			for email := range emails {
+				exclude(fmt.Sprintf("email:%s", email), calendarSource(cal.Path))
+			}
+
+			return nil
+		})
+	}
+
+	for _, path := range unavailable.Files {
+		path := path
+		eg.Go(func() error {
+			entries, err := roster.Load(path, time.Local)
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range entries {
+				if !entry.Active(now) {
+					continue
+				}
+
+				user := fmt.Sprintf("email:%s", entry.Email)
+				if entry.SlackID != "" {
+					user = fmt.Sprintf("id:%s", entry.SlackID)
+				}
+
+				exclude(user, fileSource(path))
+			}
+
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	for _, member := range append([]slackduty.Member{}, members.Members...) {
+		reason, ok := excluded[member.ID]
+		if !ok {
+			continue
+		}
+
+		members.RemoveBy(member.ID, fmt.Sprintf("exclude_unavailable/%s", reason))
+		c.logger.Info("excluded an unavailable member", zap.String("group", group.Name), zap.String("id", member.ID), zap.String("email", member.Email), zap.String("reason", reason))
+	}
+
+	return nil
+}
+
+// overriddenUsers returns the users whose slot of the schedule at the time is
+// covered by the override of someone else. Only the user of the layer that
+// would be on call without the override is replaced, the users of the lower
+// layers are not on call anyway.
+//
+// Note: PagerDuty lists the schedule layers from the highest priority.
+func (c *Client) overriddenUsers(pdClient PagerdutyClient, schedule string, t time.Time) ([]pagerduty.User, error) {
+	pdSche, err := pdClient.RenderSchedule(schedule, t, t.Add(time.Minute))
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := onCallAt(pdSche.OverrideSubschedule.RenderedScheduleEntries, t)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(overrides) == 0 {
+		return []pagerduty.User{}, nil
+	}
+
+	final, err := onCallAt(pdSche.FinalSchedule.RenderedScheduleEntries, t)
+	if err != nil {
+		return nil, err
+	}
+
+	onCall := map[string]bool{}
+	for _, shift := range append(final, overrides...) {
+		onCall[shift.ID] = true
+	}
+
+	var replaced []shift
+	for _, layer := range pdSche.ScheduleLayers {
+		replaced, err = onCallAt(layer.RenderedScheduleEntries, t)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(replaced) > 0 {
+			break
+		}
+	}
+
+	users := []pagerduty.User{}
+	for _, shift := range replaced {
+		if onCall[shift.ID] {
+			continue
+		}
+
+		user, err := pdClient.GetUser(fmt.Sprintf("id:%s", shift.ID))
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/PagerDuty/go-pagerduty"
+	"github.com/slack-go/slack"
+)
+
+const timeOffCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:pto-1
+SUMMARY:PTO carol@example.com
+DTSTART;VALUE=DATE:20200601
+DTEND;VALUE=DATE:20200606
+END:VEVENT
+BEGIN:VEVENT
+UID:pto-2
+SUMMARY:PTO
+DTSTART;VALUE=DATE:20200610
+DTEND;VALUE=DATE:20200611
+ATTENDEE:mailto:alice@example.com
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestOverriddenUsers(t *testing.T) {
+	pdClient := newFakePagerdutyClient()
+	pdClient.users["id:P1"] = &pagerduty.User{Email: "alice@example.com"}
+	layers := []pagerduty.ScheduleLayer{
+		{Name: "Primary", RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-08T09:00:00Z")}},
+	}
+	pdClient.rendered["name:overridden"] = &pagerduty.Schedule{
+		ScheduleLayers:      layers,
+		OverrideSubschedule: pagerduty.ScheduleLayer{RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P2", "2020-06-02T09:00:00Z", "2020-06-04T09:00:00Z")}},
+		FinalSchedule:       pagerduty.ScheduleLayer{RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P2", "2020-06-02T09:00:00Z", "2020-06-04T09:00:00Z")}},
+	}
+	pdClient.rendered["name:regular"] = &pagerduty.Schedule{
+		ScheduleLayers: layers,
+		FinalSchedule:  pagerduty.ScheduleLayer{RenderedScheduleEntries: layers[0].RenderedScheduleEntries},
+	}
+
+	pdClient.users["id:P3"] = &pagerduty.User{Email: "bob@example.com"}
+	stacked := []pagerduty.ScheduleLayer{
+		{Name: "Weekday", RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P3", "2020-06-03T09:00:00Z", "2020-06-03T18:00:00Z")}},
+		{Name: "Primary", RenderedScheduleEntries: layers[0].RenderedScheduleEntries},
+	}
+	override := pagerduty.ScheduleLayer{RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P2", "2020-06-03T10:00:00Z", "2020-06-03T14:00:00Z")}}
+	pdClient.rendered["name:stacked"] = &pagerduty.Schedule{
+		ScheduleLayers:      stacked,
+		OverrideSubschedule: override,
+		FinalSchedule:       override,
+	}
+	pdClient.rendered["name:stacked-gap"] = &pagerduty.Schedule{
+		ScheduleLayers:      []pagerduty.ScheduleLayer{{Name: "Weekday"}, stacked[1]},
+		OverrideSubschedule: override,
+		FinalSchedule:       override,
+	}
+	pdClient.rendered["name:self"] = &pagerduty.Schedule{
+		ScheduleLayers:      layers,
+		OverrideSubschedule: pagerduty.ScheduleLayer{RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P1", "2020-06-03T10:00:00Z", "2020-06-03T14:00:00Z")}},
+		FinalSchedule:       layers[0],
+	}
+
+	c := &Client{logger: log.NewDiscard()}
+	now := time.Date(2020, 6, 3, 12, 0, 0, 0, time.UTC)
+
+	tcs := map[string]struct {
+		schedule string
+		want     []pagerduty.User
+	}{
+		"covered by override": {"name:overridden", []pagerduty.User{{Email: "alice@example.com"}}},
+		"no override":         {"name:regular", []pagerduty.User{}},
+		"stacked layers":      {"name:stacked", []pagerduty.User{{Email: "bob@example.com"}}},
+		"gap in upper layer":  {"name:stacked-gap", []pagerduty.User{{Email: "alice@example.com"}}},
+		"override by oneself": {"name:self", []pagerduty.User{}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := c.overriddenUsers(pdClient, tc.schedule, now)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("users don't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConfigureGroup_ExcludeUnavailable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackduty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	calPath := filepath.Join(dir, "pto.ics")
+	if err := ioutil.WriteFile(calPath, []byte(timeOffCalendar), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	filePath := filepath.Join(dir, "pto.csv")
+	if err := ioutil.WriteFile(filePath, []byte("slack_id,from,until\nU4,2020-06-01,2020-06-05\nU2,2020-06-10,\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pdClient := newFakePagerdutyClient()
+	pdClient.users["id:P1"] = &pagerduty.User{Email: "alice@example.com"}
+	pdClient.teams["name:web"] = []pagerduty.User{{Email: "alice@example.com"}, {Email: "bob@example.com"}, {Email: "carol@example.com"}, {Email: "dave@example.com"}}
+	pdClient.rendered["name:web"] = &pagerduty.Schedule{
+		ScheduleLayers:      []pagerduty.ScheduleLayer{{Name: "Primary", RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P1", "2020-06-01T09:00:00Z", "2020-06-08T09:00:00Z")}}},
+		OverrideSubschedule: pagerduty.ScheduleLayer{RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P2", "2020-06-02T09:00:00Z", "2020-06-04T09:00:00Z")}},
+		FinalSchedule:       pagerduty.ScheduleLayer{RenderedScheduleEntries: []pagerduty.RenderedScheduleEntry{scheduleEntry("P2", "2020-06-02T09:00:00Z", "2020-06-04T09:00:00Z")}},
+	}
+
+	now := time.Date(2020, 6, 3, 12, 0, 0, 0, time.Local)
+
+	tcs := map[string]struct {
+		unavailable *config.Unavailable
+		want        []string
+	}{
+		"none":      {nil, []string{"U1", "U2", "U3", "U4"}},
+		"overrides": {&config.Unavailable{Schedules: []string{"name:web"}}, []string{"U2", "U3", "U4"}},
+		"calendar":  {&config.Unavailable{Calendars: []config.Calendar{{Path: calPath}}}, []string{"U1", "U2", "U4"}},
+		"file":      {&config.Unavailable{Files: []string{filePath}}, []string{"U1", "U2", "U3"}},
+		"all":       {&config.Unavailable{Schedules: []string{"name:web"}, Calendars: []config.Calendar{{Path: calPath}}, Files: []string{filePath}}, []string{"U2"}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			slackClient.users["email:alice@example.com"] = &slack.User{ID: "U1"}
+			slackClient.users["email:bob@example.com"] = &slack.User{ID: "U2"}
+			slackClient.users["email:carol@example.com"] = &slack.User{ID: "U3"}
+			slackClient.users["email:dave@example.com"] = &slack.User{ID: "U4"}
+			slackClient.usergroups["handle:web"] = []string{}
+
+			c := &Client{pagerduty: pdClient, slack: slackClient, now: func() time.Time { return now }, logger: log.NewDiscard()}
+			group := &config.Group{
+				Name:               "web",
+				ExcludeUnavailable: tc.unavailable,
+				Usergroups:         []string{"handle:web"},
+				Members:            &config.Members{Pagerduty: config.Pagerduties{{Teams: []string{"name:web"}}}},
+			}
+
+			if err := c.configureGroup(group); err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			got := append([]string{}, slackClient.usergroups["handle:web"]...)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("members don't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/calendar"
+	"github.com/KeisukeYamashita/slackduty/config"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// inWindow reports whether the time is in the window.
+func inWindow(window *config.Window, t time.Time) (bool, error) {
+	loc := time.Local
+	if window.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(window.Timezone)
+		if err != nil {
+			return false, fmt.Errorf("timezone is invalid timezone: %s error: %v", window.Timezone, err)
+		}
+	}
+	t = t.In(loc)
+
+	start, end, err := parseHours(window.Hours)
+	if err != nil {
+		return false, err
+	}
+
+	// Note: The time after the midnight of the overnight hours(e.g.
+	// 22:00-06:00) belongs to the day that the hours started.
+	minute := t.Hour()*60 + t.Minute()
+	day := t.Weekday()
+	switch {
+	case start == end:
+	case start < end:
+		if minute < start || minute >= end {
+			return false, nil
+		}
+	default:
+		if minute >= end && minute < start {
+			return false, nil
+		}
+
+		if minute < end {
+			day = (day + 6) % 7
+		}
+	}
+
+	if window.Holidays != "" {
+		holidays, err := calendar.Load(window.Holidays, loc)
+		if err != nil {
+			return false, fmt.Errorf("failed to load the holidays calendar path: %s error: %v", window.Holidays, err)
+		}
+
+		for _, holiday := range holidays {
+			if holiday.Contains(t) {
+				return false, nil
+			}
+		}
+	}
+
+	if len(window.Days) == 0 {
+		return true, nil
+	}
+
+	for _, days := range window.Days {
+		ok, err := inDays(days, day)
+		if err != nil {
+			return false, err
+		}
+
+		if ok {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// inDays reports whether the weekday is in the days(e.g. `mon-fri`, `sat`).
+func inDays(days string, day time.Weekday) (bool, error) {
+	s := strings.Split(strings.ToLower(days), "-")
+	if len(s) > 2 {
+		return false, fmt.Errorf("days is specified in wrong format days: %s", days)
+	}
+
+	from, ok := weekdays[s[0]]
+	if !ok {
+		return false, fmt.Errorf("day %s is invalid, must be sun, mon, tue, wed, thu, fri or sat", s[0])
+	}
+
+	to := from
+	if len(s) == 2 {
+		to, ok = weekdays[s[1]]
+		if !ok {
+			return false, fmt.Errorf("day %s is invalid, must be sun, mon, tue, wed, thu, fri or sat", s[1])
+		}
+	}
+
+	if from <= to {
+		return from <= day && day <= to, nil
+	}
+
+	return day >= from || day <= to, nil
+}
+
+// parseHours parses the hours(e.g. `09:00-18:00`) to the minutes of the day.
+// The whole day is returned if the hours is empty.
+func parseHours(hours string) (int, int, error) {
+	if hours == "" {
+		return 0, 0, nil
+	}
+
+	s := strings.Split(hours, "-")
+	if len(s) != 2 {
+		return 0, 0, fmt.Errorf("hours is specified in wrong format hours: %s", hours)
+	}
+
+	start, err := time.Parse("15:04", strings.TrimSpace(s[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("hours is specified in wrong format hours: %s", hours)
+	}
+
+	end, err := time.Parse("15:04", strings.TrimSpace(s[1]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("hours is specified in wrong format hours: %s", hours)
+	}
+
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+// disabledHours reports whether the usergroups of the group should be
+// disabled at the time because it is outside of the configured hours.
+func disabledHours(group *config.Group, t time.Time) (bool, error) {
+	if group.Disable == nil || group.Disable.Outside == nil {
+		return false, nil
+	}
+
+	in, err := inWindow(group.Disable.Outside, t)
+	if err != nil {
+		return false, err
+	}
+
+	return !in, nil
+}
+
+// activeMembers returns the members of the first rule whose window contains
+// the time. The members of the group are returned if no rule matches.
+func activeMembers(group *config.Group, t time.Time) (string, *config.Members, error) {
+	for i, rule := range group.Rules {
+		if rule.Window == nil {
+			return "", nil, fmt.Errorf("window of the rule is not configured rule: %d", i)
+		}
+
+		in, err := inWindow(rule.Window, t)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if !in {
+			continue
+		}
+
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rules[%d]", i)
+		}
+
+		members := rule.Members
+		if members == nil {
+			members = &config.Members{}
+		}
+
+		return name, members, nil
+	}
+
+	members := group.Members
+	if members == nil {
+		members = &config.Members{}
+	}
+
+	return "", members, nil
+}
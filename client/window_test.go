@@ -0,0 +1,159 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/log"
+)
+
+func TestInWindow(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("timezone database is not available")
+	}
+
+	businessHours := &config.Window{Days: []string{"mon-fri"}, Hours: "09:00-18:00", Timezone: "Asia/Tokyo"}
+	overnight := &config.Window{Days: []string{"fri"}, Hours: "22:00-06:00", Timezone: "Asia/Tokyo"}
+	weekend := &config.Window{Days: []string{"sat-sun"}, Timezone: "Asia/Tokyo"}
+
+	tcs := map[string]struct {
+		window *config.Window
+		t      time.Time
+		want   bool
+	}{
+		"monday morning":         {businessHours, time.Date(2026, 10, 19, 9, 0, 0, 0, tokyo), true},
+		"monday evening":         {businessHours, time.Date(2026, 10, 19, 18, 0, 0, 0, tokyo), false},
+		"saturday noon":          {businessHours, time.Date(2026, 10, 24, 12, 0, 0, 0, tokyo), false},
+		"other timezone":         {businessHours, time.Date(2026, 10, 19, 1, 0, 0, 0, time.UTC), true},
+		"friday night":           {overnight, time.Date(2026, 10, 23, 23, 0, 0, 0, tokyo), true},
+		"saturday early morning": {overnight, time.Date(2026, 10, 24, 5, 0, 0, 0, tokyo), true},
+		"friday early morning":   {overnight, time.Date(2026, 10, 23, 5, 0, 0, 0, tokyo), false},
+		"sunday":                 {weekend, time.Date(2026, 10, 25, 12, 0, 0, 0, tokyo), true},
+		"wrapping days":          {&config.Window{Days: []string{"sat-mon"}}, time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local), true},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := inWindow(tc.window, tc.t)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			if got != tc.want {
+				t.Fatalf("test %s doesn't match got: %v want: %v", n, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInWindow_Invalid(t *testing.T) {
+	tcs := map[string]*config.Window{
+		"days":     {Days: []string{"monday"}},
+		"hours":    {Hours: "9-18"},
+		"timezone": {Timezone: "Mars/Olympus"},
+	}
+
+	for n, window := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if _, err := inWindow(window, time.Now()); err == nil {
+				t.Fatalf("test %s should fail", n)
+			}
+		})
+	}
+}
+
+func TestConfigureGroup_DisableOutside(t *testing.T) {
+	tcs := map[string]struct {
+		now          time.Time
+		wantDisabled bool
+		wantMembers  string
+	}{
+		"inside":  {time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC), false, "U2"},
+		"outside": {time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC), true, "U1"},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			slackClient := newFakeSlackClient()
+			slackClient.usergroups["handle:oncall"] = []string{"U1"}
+			slackClient.disabled["handle:oncall"] = !tc.wantDisabled
+			c := &Client{slack: slackClient, logger: log.NewDiscard(), now: func() time.Time { return tc.now }}
+
+			group := &config.Group{
+				Name:       "test",
+				Disable:    &config.Disable{Outside: &config.Window{Hours: "09:00-18:00", Timezone: "UTC"}},
+				Usergroups: []string{"handle:oncall"},
+				Members:    &config.Members{Slack: &config.Slack{"id:U2"}},
+			}
+
+			if err := c.configureGroup(group); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := slackClient.disabled["handle:oncall"]; got != tc.wantDisabled {
+				t.Fatalf("disabled doesn't match got: %v want: %v", got, tc.wantDisabled)
+			}
+
+			if got := slackClient.usergroups["handle:oncall"]; got[0] != tc.wantMembers {
+				t.Fatalf("members don't match got: %v want: %v", got, tc.wantMembers)
+			}
+		})
+	}
+}
+
+func TestActiveMembers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackduty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	holidays := filepath.Join(dir, "holidays.ics")
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART;VALUE=DATE:20261103\nSUMMARY:Culture Day\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nDTSTART;VALUE=DATE:20200101\nRRULE:FREQ=YEARLY\nSUMMARY:New Year's Day\nEND:VEVENT\nEND:VCALENDAR\n"
+	if err := ioutil.WriteFile(holidays, []byte(ics), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	day := &config.Members{Slack: &config.Slack{"id:U1"}}
+	followTheSun := &config.Members{Slack: &config.Slack{"id:U2"}}
+	group := &config.Group{
+		Members: followTheSun,
+		Rules: []config.Rule{
+			{
+				Name:    "business-hours",
+				Window:  &config.Window{Days: []string{"mon-fri"}, Hours: "09:00-18:00", Timezone: "UTC", Holidays: holidays},
+				Members: day,
+			},
+		},
+	}
+
+	tcs := map[string]struct {
+		t        time.Time
+		wantRule string
+		want     *config.Members
+	}{
+		"business hours": {time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), "business-hours", day},
+		"night":          {time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC), "", followTheSun},
+		"holiday":        {time.Date(2026, 11, 3, 10, 0, 0, 0, time.UTC), "", followTheSun},
+		"yearly holiday": {time.Date(2027, 1, 1, 10, 0, 0, 0, time.UTC), "", followTheSun},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			rule, got, err := activeMembers(group, tc.t)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			if rule != tc.wantRule || got != tc.want {
+				t.Fatalf("test %s doesn't match got: %s want: %s", n, rule, tc.wantRule)
+			}
+		})
+	}
+}
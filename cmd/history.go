@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/state"
+	"go.uber.org/zap"
+)
+
+const defaultHistoryCount = 20
+
+// history prints the history of the group or the usergroup recorded in the
+// state store. With --at, it prints who was in the usergroup at the time.
+//
+//	slackduty history <group|usergroup> [--at <time>] [--count <n>]
+func history(logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	at := fs.String("at", "", "print the members at the time(e.g. 2026-10-13T03:00)")
+	count := fs.Int("count", defaultHistoryCount, "number of the latest records to print")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: slackduty history <group|usergroup> [--at <time>] [--count <n>]")
+	}
+	key := positional[0]
+
+	_, store, err := loadState(logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	if *at != "" {
+		t, _, err := config.ParseTime(*at, time.Local)
+		if err != nil {
+			return err
+		}
+
+		records, err := state.At(store, key, t)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(w, "WORKSPACE\tUSERGROUP\tRUN ID\tAPPLIED AT\tID\tEMAIL")
+		for _, record := range records {
+			for _, member := range record.Members {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", record.Workspace, record.Usergroup, record.RunID, record.Timestamp.Format(time.RFC3339), member.ID, member.Email)
+			}
+		}
+
+		return nil
+	}
+
+	records, err := store.History(key)
+	if err != nil {
+		return err
+	}
+
+	if *count > 0 && len(records) > *count {
+		records = records[len(records)-*count:]
+	}
+
+	fmt.Fprintln(w, "TIMESTAMP\tRUN ID\tWORKSPACE\tUSERGROUP\tOUTCOME\tMEMBERS\tERROR")
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", record.Timestamp.Format(time.RFC3339), record.RunID, record.Workspace, record.Usergroup, record.Outcome, len(record.Members), record.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/state"
+	"go.uber.org/zap"
+)
+
+const overrideUsage = `usage:
+  slackduty override add --group <group> --user <id:U1|email:alice@example.com> --until <time> [--reason <reason>]
+  slackduty override remove --group <group> --user <id:U1|email:alice@example.com> --until <time> [--reason <reason>]
+  slackduty override list [--group <group>] [--all]
+  slackduty override delete <id>`
+
+// override manages the overrides that add or remove a user to the computed
+// members of the group until they expire.
+func override(logger *zap.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(overrideUsage)
+	}
+
+	switch args[0] {
+	case "add":
+		return addOverride(logger, state.ActionAdd, args[1:])
+	case "remove":
+		return addOverride(logger, state.ActionRemove, args[1:])
+	case "list":
+		return listOverrides(logger, args[1:])
+	case "delete":
+		return deleteOverride(logger, args[1:])
+	default:
+		return fmt.Errorf("override command %s is invalid, must be add, remove, list or delete", args[0])
+	}
+}
+
+func addOverride(logger *zap.Logger, action state.Action, args []string) error {
+	fs := flag.NewFlagSet("override "+string(action), flag.ContinueOnError)
+	groupKey := fs.String("group", "", "group or usergroup to override")
+	user := fs.String("user", "", "Slack user(e.g. email:alice@example.com)")
+	until := fs.String("until", "", "time when the override expires(e.g. 2026-10-20T10:00)")
+	reason := fs.String("reason", "", "reason of the override")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 0 || *groupKey == "" || *user == "" || *until == "" {
+		return errors.New(overrideUsage)
+	}
+
+	if err := validateUser(*user); err != nil {
+		return err
+	}
+
+	t, _, err := config.ParseTime(*until, time.Local)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !t.After(now) {
+		return fmt.Errorf("until must be in the future until: %s", t.Format(time.RFC3339))
+	}
+
+	cfg, store, err := loadState(logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	group, err := cfg.FindGroup(*groupKey)
+	if err != nil {
+		return err
+	}
+
+	o := state.Override{
+		ID:        state.NewOverrideID(),
+		Group:     group.Key(),
+		Action:    action,
+		User:      *user,
+		Until:     t,
+		Reason:    *reason,
+		CreatedAt: now,
+	}
+
+	if err := store.AddOverride(o, now); err != nil {
+		logger.Error("failed to save the override", zap.Error(err), zap.String("group", o.Group))
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "override %s: %s %s to %s until %s\n", o.ID, o.Action, o.User, o.Group, o.Until.Format(time.RFC3339))
+	return nil
+}
+
+func listOverrides(logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("override list", flag.ContinueOnError)
+	groupKey := fs.String("group", "", "group or usergroup of the overrides")
+	all := fs.Bool("all", false, "print the expired overrides too")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg, store, err := loadState(logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	key := ""
+	if *groupKey != "" {
+		group, err := cfg.FindGroup(*groupKey)
+		if err != nil {
+			return err
+		}
+		key = group.Key()
+	}
+
+	overrides, err := store.Overrides(key)
+	if err != nil {
+		return err
+	}
+
+	if !*all {
+		overrides = state.Active(overrides, time.Now())
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "ID\tGROUP\tACTION\tUSER\tUNTIL\tREASON")
+	for _, o := range overrides {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", o.ID, o.Group, o.Action, o.User, o.Until.Format(time.RFC3339), o.Reason)
+	}
+
+	return nil
+}
+
+func deleteOverride(logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("override delete", flag.ContinueOnError)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New(overrideUsage)
+	}
+
+	_, store, err := loadState(logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if err := store.DeleteOverride(positional[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "deleted override %s\n", positional[0])
+	return nil
+}
+
+// validateUser validates the Slack user selector of the override.
+func validateUser(user string) error {
+	s := strings.Split(user, ":")
+	if len(s) != 2 || (s[0] != "id" && s[0] != "email") {
+		return fmt.Errorf("user is specified in wrong format, must be id or email user: %s", user)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/KeisukeYamashita/slackduty/client"
+	"github.com/KeisukeYamashita/slackduty/state"
+	"go.uber.org/zap"
+)
+
+// plan prints the members that the sync of the group will apply and the
+// sources of each member without updating the usergroups.
+//
+//	slackduty plan <group|usergroup>
+func plan(logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: slackduty plan <group|usergroup>")
+	}
+
+	c, closeFn, err := newReadOnlyClient(logger)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	plans, err := c.Plan(positional[0])
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	for i, p := range plans {
+		if i != 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "workspace: %s usergroup: %s action: %s\n", p.Workspace, p.Usergroup, p.Action)
+		if p.Reason != "" {
+			fmt.Fprintf(w, "reason: %s\n", p.Reason)
+		}
+
+		added := map[string]bool{}
+		for _, id := range p.Added {
+			added[id] = true
+		}
+
+		fmt.Fprintln(w, "\tID\tEMAIL\tSOURCES")
+		for _, member := range p.Members {
+			mark := " "
+			if added[member.ID] {
+				mark = "+"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", mark, member.ID, member.Email, strings.Join(member.Sources, ","))
+		}
+
+		for _, id := range p.Removed {
+			fmt.Fprintf(w, "-\t%s\t\t\n", id)
+		}
+
+		for _, member := range p.Excluded {
+			fmt.Fprintf(w, "x\t%s\t%s\tremoved by %s\n", member.ID, member.Email, member.RemovedBy)
+		}
+	}
+
+	return nil
+}
+
+// why prints why the user is or isn't a member of the usergroups of the
+// group.
+//
+//	slackduty why <group|usergroup> <id:U1|email:alice@example.com>
+func why(logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("why", flag.ContinueOnError)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 2 {
+		return errors.New("usage: slackduty why <group|usergroup> <id:U1|email:alice@example.com>")
+	}
+
+	key, user := positional[0], positional[1]
+	if err := validateUser(user); err != nil {
+		return err
+	}
+
+	c, closeFn, err := newReadOnlyClient(logger)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	reasons, err := c.Why(key, user)
+	if err != nil {
+		return err
+	}
+
+	for _, reason := range reasons {
+		because := "no source resolves the user"
+		if reason.RemovedBy != "" {
+			because = fmt.Sprintf("the user is removed by %s", reason.RemovedBy)
+		}
+
+		switch {
+		case reason.Member:
+			fmt.Fprintf(os.Stdout, "%s is a member of %s in workspace %s because of:\n", user, reason.Usergroup, reason.Workspace)
+		case reason.Current:
+			fmt.Fprintf(os.Stdout, "%s is in %s in workspace %s now but will be removed because %s\n", user, reason.Usergroup, reason.Workspace, because)
+		default:
+			fmt.Fprintf(os.Stdout, "%s is not a member of %s in workspace %s because %s\n", user, reason.Usergroup, reason.Workspace, because)
+		}
+
+		if reason.RemovedBy != "" {
+			fmt.Fprintln(os.Stdout, "  it is resolved from:")
+		}
+
+		for _, source := range reason.Sources {
+			fmt.Fprintf(os.Stdout, "  - %s\n", source)
+		}
+
+		if reason.Paused != "" {
+			fmt.Fprintf(os.Stdout, "  the usergroup is left as it is because the group is %s\n", reason.Paused)
+		}
+	}
+
+	return nil
+}
+
+// newReadOnlyClient creates a client for the commands that don't update the
+// usergroups. The state store is opened if it is configured so that the
+// overrides are applied.
+func newReadOnlyClient(logger *zap.Logger) (*client.Client, func(), error) {
+	cfg, err := loadConfig(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var store state.Store
+	if cfg.State != nil {
+		store, err = newStore(cfg.State)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	c, err := newManualClient(logger, cfg, store)
+	if err != nil {
+		if store != nil {
+			store.Close()
+		}
+		return nil, nil, err
+	}
+
+	return c, func() {
+		if store != nil {
+			store.Close()
+		}
+	}, nil
+}
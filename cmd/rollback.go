@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/client"
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/state"
+	"go.uber.org/zap"
+)
+
+// rollback restores the usergroups of the group to the members of a previous
+// record and pauses the automatic sync of the group until it is resumed.
+// Without --to, it restores the record before the last applied one.
+//
+//	slackduty rollback <group|usergroup> [--to <time|run-id>]
+func rollback(logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("rollback", flag.ContinueOnError)
+	to := fs.String("to", "", "time(e.g. 2026-10-13T03:00) or run id to restore")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: slackduty rollback <group|usergroup> [--to <time|run-id>]")
+	}
+	key := positional[0]
+
+	cfg, store, err := loadState(logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var records []state.Record
+	switch {
+	case *to == "":
+		records, err = state.Previous(store, key)
+	default:
+		if t, _, timeErr := config.ParseTime(*to, time.Local); timeErr == nil {
+			records, err = state.At(store, key, t)
+			if err == nil && len(records) == 0 {
+				err = fmt.Errorf("no applied record of %s at %s", key, t.Format(time.RFC3339))
+			}
+		} else {
+			records, err = state.Run(store, key, *to)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c, err := newManualClient(logger, cfg, store)
+	if err != nil {
+		return err
+	}
+
+	runID, err := c.Rollback(key, records)
+	if err != nil {
+		logger.Error("failed to roll back the group", zap.Error(err), zap.String("group", key))
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "WORKSPACE\tUSERGROUP\tRESTORED RUN ID\tAPPLIED AT\tMEMBERS")
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", record.Workspace, record.Usergroup, record.RunID, record.Timestamp.Format(time.RFC3339), len(record.Members))
+	}
+	fmt.Fprintf(w, "\nrolled back by run %s, the sync is paused until `slackduty resume %s`\n", runID, key)
+
+	return nil
+}
+
+// resume resumes the automatic sync of the group paused by the rollback.
+//
+//	slackduty resume <group|usergroup>
+func resume(logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("resume", flag.ContinueOnError)
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: slackduty resume <group|usergroup>")
+	}
+	key := positional[0]
+
+	cfg, store, err := loadState(logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	c, err := newManualClient(logger, cfg, store)
+	if err != nil {
+		return err
+	}
+
+	if err := c.Resume(key); err != nil {
+		logger.Error("failed to resume the group", zap.Error(err), zap.String("group", key))
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "resumed the sync of %s\n", key)
+	return nil
+}
+
+// loadState loads the config and opens the state store configured in it.
+func loadState(logger *zap.Logger) (*config.Config, state.Store, error) {
+	cfg, err := loadConfig(logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.State == nil {
+		return nil, nil, errors.New("state is not configured in the config")
+	}
+
+	store, err := newStore(cfg.State)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, store, nil
+}
+
+// newManualClient creates a client for the commands run by hand. It never
+// starts the cronjob.
+func newManualClient(logger *zap.Logger, cfg *config.Config, store state.Store) (*client.Client, error) {
+	resolver, err := newResolver(cfg)
+	if err != nil {
+		logger.Error("failed to configure the secret providers", zap.Error(err))
+		return nil, err
+	}
+
+	creds, err := resolveCredentials(cfg, resolver)
+	if err != nil {
+		logger.Error("failed to load API key", zap.Error(err))
+		return nil, err
+	}
+
+	opts := append([]client.ClientOption{client.WithExternalTrigger(), client.WithStore(store)}, creds.opts...)
+	c, err := client.New(cfg, creds.pdAPIKey, creds.slackAPIKey, logger, opts...)
+	if err != nil {
+		logger.Error("failed to create the client", zap.Error(err))
+		return nil, err
+	}
+
+	return c, nil
+}
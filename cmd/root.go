@@ -1,31 +1,78 @@
 package cmd
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/KeisukeYamashita/slackduty/client"
 	"github.com/KeisukeYamashita/slackduty/config"
 	"github.com/KeisukeYamashita/slackduty/log"
+	"github.com/KeisukeYamashita/slackduty/state"
 	"go.uber.org/zap"
 )
 
-// Execute will run the slackduty job
+// fileWatchInterval is how often the roster files are checked for changes.
+const fileWatchInterval = time.Minute
+
+// Execute will run the slackduty command given by the arguments.
+// Without a command, it runs the slackduty job.
 func Execute() error {
 	logger, err := log.New("INFO")
 	if err != nil {
 		return err
 	}
 
-	return execute(logger)
+	return execute(logger, os.Args[1:])
 }
 
-func execute(logger *zap.Logger) error {
-	path := config.GetConfigPath()
-	cfg, err := config.Load(path)
+func execute(logger *zap.Logger, args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return run(logger, args)
+	}
+
+	switch args[0] {
+	case "run":
+		return run(logger, args[1:])
+	case "history":
+		return history(logger, args[1:])
+	case "rollback":
+		return rollback(logger, args[1:])
+	case "resume":
+		return resume(logger, args[1:])
+	case "override":
+		return override(logger, args[1:])
+	case "plan":
+		return plan(logger, args[1:])
+	case "why":
+		return why(logger, args[1:])
+	default:
+		return fmt.Errorf("command %s is invalid, must be run, plan, why, history, rollback, resume or override", args[0])
+	}
+}
+
+func run(logger *zap.Logger, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	force := fs.Bool("force", false, "apply the members even if they violate the guards of the groups")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(logger)
 	if err != nil {
-		logger.Error("failed to load config", zap.Error(err))
 		return err
 	}
 
-	pdAPIKey, slackAPIKey, err := config.GetAPIKeys()
+	resolver, err := newResolver(cfg)
+	if err != nil {
+		logger.Error("failed to configure the secret providers", zap.Error(err))
+		return err
+	}
+
+	creds, err := resolveCredentials(cfg, resolver)
 	if err != nil {
 		logger.Error("failed to load API key", zap.Error(err))
 		return err
@@ -37,6 +84,75 @@ func execute(logger *zap.Logger) error {
 		opts = append(opts, client.WithExternalTrigger())
 	}
 
-	client := client.New(cfg, pdAPIKey, slackAPIKey, logger, opts...)
+	if *force {
+		logger.Warn("the guards of the groups are disabled for the next run by --force")
+		opts = append(opts, client.WithForce())
+	}
+
+	opts = append(opts, creds.opts...)
+
+	if cfg.State != nil {
+		store, err := newStore(cfg.State)
+		if err != nil {
+			logger.Error("failed to open the state store", zap.Error(err))
+			return err
+		}
+		defer store.Close()
+
+		opts = append(opts, client.WithStore(store))
+	}
+
+	client, err := client.New(cfg, creds.pdAPIKey, creds.slackAPIKey, logger, opts...)
+	if err != nil {
+		logger.Error("failed to create the client", zap.Error(err))
+		return err
+	}
+
+	if !config.IsExternalTrigger() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go watchCredentials(ctx, resolver, creds, client, logger)
+		go client.WatchFiles(ctx, fileWatchInterval)
+	}
+
 	return client.Run()
 }
+
+func loadConfig(logger *zap.Logger) (*config.Config, error) {
+	path := config.GetConfigPath()
+	cfg, err := config.Load(path)
+	if err != nil {
+		logger.Error("failed to load config", zap.Error(err))
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func newStore(cfg *config.State) (state.Store, error) {
+	switch cfg.Type {
+	case "", "file":
+		return state.NewFileStore(config.GetStatePath(cfg), cfg.HistoryLimit)
+	default:
+		return nil, fmt.Errorf("state type %s is invalid, must be file", cfg.Type)
+	}
+}
+
+// parseFlags parses the flags even if they are placed after the positional
+// arguments and returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
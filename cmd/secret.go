@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/client"
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/secret"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultVaultTokenEnv = "VAULT_TOKEN"
+	secretWatchInterval  = time.Minute
+)
+
+// credentials are the API keys resolved from the config. rotations keeps how
+// to rotate the API key for each secret reference.
+type credentials struct {
+	pdAPIKey    string
+	slackAPIKey string
+	opts        []client.ClientOption
+	rotations   map[string][]func(*client.Client, string) error
+}
+
+func newResolver(cfg *config.Config) (*secret.Resolver, error) {
+	opts := []secret.ResolverOption{}
+	if cfg.Secrets != nil && cfg.Secrets.Vault != nil {
+		vault := cfg.Secrets.Vault
+		if vault.Address == "" {
+			return nil, errors.New("secrets.vault.address is not configured")
+		}
+
+		tokenEnv := vault.TokenEnv
+		if tokenEnv == "" {
+			tokenEnv = defaultVaultTokenEnv
+		}
+
+		token := os.Getenv(tokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("%s is not configured for the vault secret provider", tokenEnv)
+		}
+
+		opts = append(opts, secret.WithProvider("vault", secret.NewVaultProvider(vault.Address, token)))
+	}
+
+	return secret.NewResolver(opts...), nil
+}
+
+// resolveCredentials resolves the API keys of the default PagerDuty account,
+// Opsgenie account and Slack workspace, and the named ones in the config.
+func resolveCredentials(cfg *config.Config, resolver *secret.Resolver) (*credentials, error) {
+	creds := &credentials{
+		rotations: map[string][]func(*client.Client, string) error{},
+	}
+
+	if cfg.APIKeys == nil {
+		pdAPIKey, slackAPIKey, err := config.GetAPIKeys()
+		if err != nil {
+			return nil, err
+		}
+
+		creds.pdAPIKey = pdAPIKey
+		creds.slackAPIKey = slackAPIKey
+	} else {
+		pdRef := cfg.APIKeys.Pagerduty
+		if pdRef == "" {
+			pdRef = "env:SLACKDUTY_PAGERDUTY_API_KEY"
+		}
+
+		pdAPIKey, err := resolver.Resolve(pdRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the PagerDuty API key: %v", err)
+		}
+
+		slackRef := cfg.APIKeys.Slack
+		if slackRef == "" {
+			slackRef = "env:SLACKDUTY_SLACK_API_KEY"
+		}
+
+		slackAPIKey, err := resolver.Resolve(slackRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the Slack API key: %v", err)
+		}
+
+		creds.pdAPIKey = pdAPIKey
+		creds.slackAPIKey = slackAPIKey
+		creds.addRotation(pdRef, func(c *client.Client, apiKey string) error {
+			return c.SetPagerdutyAPIKey("", apiKey)
+		})
+		creds.addRotation(slackRef, func(c *client.Client, apiKey string) error {
+			return c.SetSlackAPIKey("", apiKey)
+		})
+	}
+
+	for _, account := range cfg.Accounts {
+		account := account
+		var apiKey string
+		var err error
+		if account.APIKey != "" {
+			apiKey, err = resolver.Resolve(account.APIKey)
+			creds.addRotation(account.APIKey, func(c *client.Client, apiKey string) error {
+				return c.SetPagerdutyAPIKey(account.Name, apiKey)
+			})
+		} else {
+			apiKey, err = config.GetAccountAPIKey(account)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the API key of PagerDuty account %s: %v", account.Name, err)
+		}
+
+		creds.opts = append(creds.opts, client.WithPagerdutyAccount(account.Name, apiKey))
+	}
+
+	if cfg.Opsgenie != nil {
+		ogRef := cfg.Opsgenie.APIKey
+		if ogRef == "" {
+			ogRef = "env:SLACKDUTY_OPSGENIE_API_KEY"
+		}
+
+		ogAPIKey, err := resolver.Resolve(ogRef)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the Opsgenie API key: %v", err)
+		}
+
+		creds.opts = append(creds.opts, client.WithOpsgenie(ogAPIKey, cfg.Opsgenie.URL))
+		creds.addRotation(ogRef, func(c *client.Client, apiKey string) error {
+			return c.SetOpsgenieAPIKey("", apiKey)
+		})
+	}
+
+	for _, account := range cfg.OpsgenieAccounts {
+		account := account
+		var apiKey string
+		var err error
+		if account.APIKey != "" {
+			apiKey, err = resolver.Resolve(account.APIKey)
+			creds.addRotation(account.APIKey, func(c *client.Client, apiKey string) error {
+				return c.SetOpsgenieAPIKey(account.Name, apiKey)
+			})
+		} else {
+			apiKey, err = config.GetOpsgenieAccountAPIKey(account)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the API key of Opsgenie account %s: %v", account.Name, err)
+		}
+
+		creds.opts = append(creds.opts, client.WithOpsgenieAccount(account.Name, apiKey, account.URL))
+	}
+
+	for _, workspace := range cfg.Workspaces {
+		workspace := workspace
+		var apiKey string
+		var err error
+		if workspace.APIKey != "" {
+			apiKey, err = resolver.Resolve(workspace.APIKey)
+			creds.addRotation(workspace.APIKey, func(c *client.Client, apiKey string) error {
+				return c.SetSlackAPIKey(workspace.Name, apiKey)
+			})
+		} else {
+			apiKey, err = config.GetWorkspaceAPIKey(workspace)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve the API key of Slack workspace %s: %v", workspace.Name, err)
+		}
+
+		creds.opts = append(creds.opts, client.WithWorkspace(workspace.Name, apiKey, workspace.TeamID))
+	}
+
+	return creds, nil
+}
+
+func (c *credentials) addRotation(ref string, fn func(*client.Client, string) error) {
+	c.rotations[ref] = append(c.rotations[ref], fn)
+}
+
+// watchCredentials rotates the API keys of the client when the secret files
+// are changed. The secret values are never logged.
+func watchCredentials(ctx context.Context, resolver *secret.Resolver, creds *credentials, c *client.Client, logger *zap.Logger) {
+	refs := []string{}
+	for ref := range creds.rotations {
+		refs = append(refs, ref)
+	}
+
+	resolver.Watch(ctx, secretWatchInterval, refs, func(ref, apiKey string) {
+		for _, fn := range creds.rotations[ref] {
+			if err := fn(c, apiKey); err != nil {
+				logger.Error("failed to rotate the API key", zap.Error(err), zap.String("secret", ref))
+				continue
+			}
+
+			logger.Info("rotated the API key", zap.String("secret", ref))
+		}
+	}, func(ref string, err error) {
+		logger.Warn("failed to re-read the secret", zap.Error(err), zap.String("secret", ref))
+	})
+}
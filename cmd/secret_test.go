@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"github.com/KeisukeYamashita/slackduty/secret"
+)
+
+func TestResolveCredentials(t *testing.T) {
+	os.Setenv("SLACKDUTY_TEST_SLACK", "slack-secret")
+	defer os.Unsetenv("SLACKDUTY_TEST_SLACK")
+	os.Setenv("SLACKDUTY_TEST_PAGERDUTY", "pagerduty-secret")
+	defer os.Unsetenv("SLACKDUTY_TEST_PAGERDUTY")
+
+	tcs := map[string]struct {
+		input     string
+		wantPD    string
+		wantSlack string
+		success   bool
+	}{
+		"env reference": {"api_keys:\n  pagerduty: env:SLACKDUTY_TEST_PAGERDUTY\n  slack: env:SLACKDUTY_TEST_SLACK\n", "pagerduty-secret", "slack-secret", true},
+		"env expansion": {"api_keys:\n  pagerduty: ${SLACKDUTY_TEST_PAGERDUTY}\n  slack: \"${SLACKDUTY_TEST_SLACK}\"\n", "pagerduty-secret", "slack-secret", true},
+		"partial ref":   {"api_keys:\n  pagerduty: env:${SLACKDUTY_TEST_PAGERDUTY}\n  slack: env:SLACKDUTY_TEST_SLACK\n", "", "", false},
+		"wrong format":  {"api_keys:\n  pagerduty: env:SLACKDUTY_TEST_PAGERDUTY\n  slack: slack-secret\n", "", "", false},
+		"env not set":   {"api_keys:\n  pagerduty: ${SLACKDUTY_TEST_NOT_SET}\n  slack: env:SLACKDUTY_TEST_SLACK\n", "", "", false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "slackduty-config-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+
+			if _, err := f.WriteString(tc.input); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			cfg, err := config.Load(f.Name())
+			if err == nil {
+				var creds *credentials
+				creds, err = resolveCredentials(cfg, secret.NewResolver())
+				if err == nil {
+					if !tc.success {
+						t.Fatalf("test %s should fail", n)
+					}
+
+					if creds.pdAPIKey != tc.wantPD {
+						t.Fatalf("pagerduty API key doesn't match got: %s want: %s", creds.pdAPIKey, tc.wantPD)
+					}
+
+					if creds.slackAPIKey != tc.wantSlack {
+						t.Fatalf("slack API key doesn't match got: %s want: %s", creds.slackAPIKey, tc.wantSlack)
+					}
+					return
+				}
+			}
+
+			if tc.success {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+		})
+	}
+}
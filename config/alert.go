@@ -0,0 +1,22 @@
+package config
+
+// Alert configures the notifications sent when Slackduty fails to
+// synchronize a group. Slack and PagerDuty can be configured together.
+type Alert struct {
+	Slack     *SlackAlert     `yaml:"slack"`
+	Pagerduty *PagerdutyAlert `yaml:"pagerduty"`
+}
+
+// SlackAlert posts the failure to a Slack channel.
+// The default workspace is used if the workspace is not specified.
+type SlackAlert struct {
+	Channel   string `yaml:"channel"`
+	Workspace string `yaml:"workspace"`
+}
+
+// PagerdutyAlert triggers a PagerDuty Events v2 alert for the failure.
+// The alert is deduplicated per group and resolved on the next success.
+type PagerdutyAlert struct {
+	RoutingKey string `yaml:"routing_key"`
+	Severity   string `yaml:"severity"`
+}
@@ -0,0 +1,30 @@
+package config
+
+// Calendar is the iCalendar file whose events select the members. The
+// attendees and the emails in the summary of the events at the execution time
+// are the members. Match filters the events by the regular expression of the
+// summary. The dates and the times without the timezone are in the Timezone
+// (default local).
+type Calendar struct {
+	Path     string `yaml:"path"`
+	Match    string `yaml:"match"`
+	Timezone string `yaml:"timezone"`
+}
+
+// UnmarshalYAML accepts both the path and the calendar with the options.
+func (c *Calendar) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var path string
+	if err := unmarshal(&path); err == nil {
+		*c = Calendar{Path: path}
+		return nil
+	}
+
+	type calendar Calendar
+	var cal calendar
+	if err := unmarshal(&cal); err != nil {
+		return err
+	}
+
+	*c = Calendar(cal)
+	return nil
+}
@@ -1,19 +1,21 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v2"
 )
 
 func sweepEnvs() {
 	os.Setenv("SLACKDUTY_CONFIG", "")
 	os.Setenv("SLACKDUTY_PAGERDUTY_API_KEY", "")
 	os.Setenv("SLACKDUTY_SLACK_API_KEY", "")
-	os.Setenv("SLACKDUTY_EXTENRAL_TRIGGER", "")
+	os.Setenv("SLACKDUTY_EXTERNAL_TRIGGER", "")
 }
 
 func TestLoad(t *testing.T) {
@@ -23,10 +25,12 @@ func TestLoad(t *testing.T) {
 			Usergroups: []string{"handle:slackduty-on-support"},
 			Schedule:   "* * * * *",
 			Members: &Members{
-				Pagerduty: &Pagerduty{
-					Teams:     []string{"name:slackdutyPrimary"},
-					Services:  []string{"name:slackduty-backend"},
-					Schedules: []string{"name:slackduty-oncall"},
+				Pagerduty: Pagerduties{
+					{
+						Teams:     []string{"name:slackdutyPrimary"},
+						Services:  []string{"name:slackduty-backend"},
+						Schedules: []Schedule{{Schedule: "name:slackduty-oncall"}},
+					},
 				},
 			},
 			Exclude: []string{"name:slackduty@example.com"},
@@ -61,6 +65,158 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestPagerduties_UnmarshalYAML(t *testing.T) {
+	tcs := map[string]struct {
+		input   string
+		want    Pagerduties
+		success bool
+	}{
+		"single": {"teams: [\"name:web\"]", Pagerduties{{Teams: []string{"name:web"}}}, true},
+		"list": {"- teams: [\"name:web\"]\n- account: legacy\n  teams: [\"name:web\"]", Pagerduties{
+			{Teams: []string{"name:web"}},
+			{Account: "legacy", Teams: []string{"name:web"}},
+		}, true},
+		"invalid": {"name:web", nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			var got Pagerduties
+			err := yaml.Unmarshal([]byte(tc.input), &got)
+			if err != nil {
+				if tc.success {
+					t.Fatalf("test %s error: %v", n, err)
+				} else {
+					return
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				diff := cmp.Diff(got, tc.want)
+				t.Fatalf("unmarshal result unexpected diff:%v", diff)
+			}
+		})
+	}
+}
+
+func TestOpsgenies_UnmarshalYAML(t *testing.T) {
+	tcs := map[string]struct {
+		input   string
+		want    Opsgenies
+		success bool
+	}{
+		"single": {"teams: [\"name:web\"]", Opsgenies{{Teams: []string{"name:web"}}}, true},
+		"list": {"- teams: [\"name:web\"]\n- account: eu\n  schedules: [\"name:web-oncall\"]", Opsgenies{
+			{Teams: []string{"name:web"}},
+			{Account: "eu", Schedules: []string{"name:web-oncall"}},
+		}, true},
+		"invalid": {"name:web", nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			var got Opsgenies
+			err := yaml.Unmarshal([]byte(tc.input), &got)
+			if err != nil {
+				if tc.success {
+					t.Fatalf("test %s error: %v", n, err)
+				} else {
+					return
+				}
+			}
+
+			if !reflect.DeepEqual(got, tc.want) {
+				diff := cmp.Diff(got, tc.want)
+				t.Fatalf("unmarshal result unexpected diff:%v", diff)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	os.Setenv("SLACKDUTY_TEST_ROUTING_KEY", "routing_key")
+	defer os.Unsetenv("SLACKDUTY_TEST_ROUTING_KEY")
+
+	tcs := map[string]struct {
+		input   string
+		want    string
+		success bool
+	}{
+		"no reference": {"env:SLACKDUTY_PAGERDUTY_API_KEY", "env:SLACKDUTY_PAGERDUTY_API_KEY", true},
+		"reference":    {"${SLACKDUTY_TEST_ROUTING_KEY}", "routing_key", true},
+		"partial":      {"key-${SLACKDUTY_TEST_ROUTING_KEY}", "key-routing_key", true},
+		"not braced":   {"$SLACKDUTY_TEST_ROUTING_KEY", "$SLACKDUTY_TEST_ROUTING_KEY", true},
+		"not set":      {"${SLACKDUTY_TEST_NOT_SET}", "", false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := expandEnv(tc.input)
+			if err != nil {
+				if tc.success {
+					t.Fatalf("test %s error: %v", n, err)
+				} else {
+					return
+				}
+			}
+
+			if got != tc.want {
+				t.Fatalf("expanded result unexpected got: %s want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoad_ExpandCredentials(t *testing.T) {
+	os.Setenv("SLACKDUTY_TEST_ROUTING_KEY", "s3cr3t")
+	defer os.Unsetenv("SLACKDUTY_TEST_ROUTING_KEY")
+
+	tcs := map[string]struct {
+		input   string
+		want    string
+		success bool
+	}{
+		"credential field": {"alert:\n  pagerduty:\n    routing_key: ${SLACKDUTY_TEST_ROUTING_KEY}\n", "s3cr3t", true},
+		"other field":      {"alert:\n  pagerduty:\n    routing_key: key\n    severity: ${SLACKDUTY_TEST_ROUTING_KEY}\n", "key", true},
+		"parse error":      {"alert:\n  pagerduty: ${SLACKDUTY_TEST_ROUTING_KEY}\n", "", false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "slackduty-config-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+
+			if _, err := f.WriteString(tc.input); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			got, err := Load(f.Name())
+			if err != nil {
+				if tc.success {
+					t.Fatalf("test %s error: %v", n, err)
+				}
+
+				if strings.Contains(err.Error(), "s3cr3t") {
+					t.Fatalf("error has the secret: %v", err)
+				}
+				return
+			}
+
+			if !tc.success {
+				t.Fatalf("test %s should fail", n)
+			}
+
+			if got.Alert.Pagerduty.RoutingKey != tc.want {
+				t.Fatalf("routing key doesn't match got: %s want: %s", got.Alert.Pagerduty.RoutingKey, tc.want)
+			}
+		})
+	}
+}
+
 func TestGetConfigPath(t *testing.T) {
 	tcs := map[string]struct {
 		fn   func()
@@ -126,13 +282,39 @@ func TestGetAPIKeys(t *testing.T) {
 	}
 }
 
+func TestGetWorkspaceAPIKey(t *testing.T) {
+	tcs := map[string]struct {
+		fn        func()
+		workspace Workspace
+		want      string
+		success   bool
+	}{
+		"no env":         {func() {}, Workspace{Name: "eu"}, "", false},
+		"env not set":    {func() {}, Workspace{Name: "eu", APIKeyEnv: "SLACKDUTY_SLACK_API_KEY"}, "", false},
+		"env configured": {func() { os.Setenv("SLACKDUTY_SLACK_API_KEY", "slack_key") }, Workspace{Name: "eu", APIKeyEnv: "SLACKDUTY_SLACK_API_KEY"}, "slack_key", true},
+	}
+
+	for n, tc := range tcs {
+		tc.fn()
+		got, err := GetWorkspaceAPIKey(tc.workspace)
+		if (err == nil) != tc.success {
+			t.Fatalf("test %s unexpected error: %v", n, err)
+		}
+
+		if got != tc.want {
+			t.Fatalf("not expected API key %s got: %s want: %s", n, got, tc.want)
+		}
+		sweepEnvs()
+	}
+}
+
 func TestIsExternalTrigger(t *testing.T) {
 	tcs := map[string]struct {
 		fn   func()
 		want bool
 	}{
 		"default":   {func() {}, false},
-		"conifgure": {func() { os.Setenv("SLACKDUTY_EXTENRAL_TRIGGER", "true") }, true},
+		"conifgure": {func() { os.Setenv("SLACKDUTY_EXTERNAL_TRIGGER", "true") }, true},
 	}
 
 	for n, tc := range tcs {
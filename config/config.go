@@ -2,40 +2,100 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"os/user"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
 
 var (
 	defaultConfigPath = "/.slackduty/config.yml"
+	defaultStatePath  = "/.slackduty/state.json"
 )
 
 // Config is the CLI configuration kept in SLACKDUTY_CONFIG(default value is )
 type Config struct {
-	Groups []Group `yaml:"groups"`
+	Accounts         []Account         `yaml:"accounts"`
+	Alert            *Alert            `yaml:"alert"`
+	APIKeys          *APIKeys          `yaml:"api_keys"`
+	Groups           []Group           `yaml:"groups"`
+	Opsgenie         *OpsgenieAccount  `yaml:"opsgenie"`
+	OpsgenieAccounts []OpsgenieAccount `yaml:"opsgenie_accounts"`
+	Secrets          *Secrets          `yaml:"secrets"`
+	State            *State            `yaml:"state"`
+	Workspaces       []Workspace       `yaml:"workspaces"`
 }
 
 // Group represents one single rule for syncronizing.
 // A group will syncronize with the same fetch schedule.
 type Group struct {
-	Name       string   `yaml:"name"`
-	Exclude    []string `yaml:"exclude"`
-	Members    *Members `yaml:"members"`
-	Schedule   string   `yaml:"schedule"`
-	Usergroups []string `yaml:"usergroups"`
+	Name               string           `yaml:"name"`
+	Disable            *Disable         `yaml:"disable"`
+	Exclude            []string         `yaml:"exclude"`
+	ExcludeAccounts    *ExcludeAccounts `yaml:"exclude_accounts"`
+	ExcludeUnavailable *Unavailable     `yaml:"exclude_unavailable"`
+	Fallback           *Members         `yaml:"fallback"`
+	Guard              *Guard           `yaml:"guard"`
+	Members            *Members         `yaml:"members"`
+	Notify             *Notify          `yaml:"notify"`
+	OnEmpty            string           `yaml:"on_empty"`
+	Rules              []Rule           `yaml:"rules"`
+	Schedule           string           `yaml:"schedule"`
+	Usergroups         []string         `yaml:"usergroups"`
+	Workspaces         []string         `yaml:"workspaces"`
+}
+
+// Key returns the name of the group. It falls back to the usergroups
+// because the name of the group is optional.
+func (g *Group) Key() string {
+	if g.Name != "" {
+		return g.Name
+	}
+
+	return strings.Join(g.Usergroups, ",")
+}
+
+// FindGroup returns the group by the name or one of the usergroups.
+func (c *Config) FindGroup(key string) (*Group, error) {
+	for i := range c.Groups {
+		group := &c.Groups[i]
+		if group.Key() == key {
+			return group, nil
+		}
+
+		for _, usergroup := range group.Usergroups {
+			if usergroup == key {
+				return group, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("group is not configured group: %s", key)
 }
 
 // Members represents the Slack or Pagerduty user which belongs
-// to the handle(s) defined in the same group.
+// to the handle(s) defined in the same group. Calendars are the iCalendar
+// files and Files are the paths of the CSV or YAML rosters.
+// The members can be composed by the set operations. The members of Union
+// are added, then the members not in every Intersect are removed, and the
+// members of Subtract are removed at last.
 type Members struct {
-	Slack     *Slack     `yaml:"slack"`
-	Pagerduty *Pagerduty `yaml:"pagerduty"`
+	Slack     *Slack      `yaml:"slack"`
+	Calendars []Calendar  `yaml:"calendars"`
+	Files     []string    `yaml:"files"`
+	Opsgenie  Opsgenies   `yaml:"opsgenie"`
+	Pagerduty Pagerduties `yaml:"pagerduty"`
+	Union     []*Members  `yaml:"union"`
+	Intersect []*Members  `yaml:"intersect"`
+	Subtract  []*Members  `yaml:"subtract"`
 }
 
 // Load loads the config.yml from the filepath given.
+// The `${NAME}` references in the credential fields are replaced by the
+// environment variables after parsing.
 func Load(path string) (*Config, error) {
 	cfg := &Config{}
 
@@ -48,6 +108,10 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := cfg.expandCredentials(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
 
@@ -57,16 +121,27 @@ func GetConfigPath() string {
 		return path
 	}
 
-	userHome := ""
+	return getHomeDir() + defaultConfigPath
+}
+
+func getHomeDir() string {
 	usr, err := user.Current()
 	if err != nil {
 		// Fallback by reading $HOME environment variables
-		userHome = os.Getenv("HOME")
-	} else {
-		userHome = usr.HomeDir
+		return os.Getenv("HOME")
 	}
 
-	return userHome + defaultConfigPath
+	return usr.HomeDir
+}
+
+// GetStatePath retrieves the path of the state file. The default path is
+// in the same directory as the default config.yml.
+func GetStatePath(state *State) string {
+	if state != nil && state.Path != "" {
+		return state.Path
+	}
+
+	return getHomeDir() + defaultStatePath
 }
 
 // GetAPIKeys retrieves the API key from environment variables
@@ -82,6 +157,37 @@ func GetAPIKeys() (pdAPIKey, slackAPIKey string, err error) {
 	return pdAPIKey, slackAPIKey, nil
 }
 
+// GetAccountAPIKey retrieves the API key of the PagerDuty account from the
+// environment variable configured in the account.
+func GetAccountAPIKey(account Account) (string, error) {
+	return getAPIKeyEnv(account.APIKeyEnv, "account", account.Name)
+}
+
+// GetOpsgenieAccountAPIKey retrieves the API key of the named Opsgenie account
+// from the environment variable configured in the account.
+func GetOpsgenieAccountAPIKey(account OpsgenieAccount) (string, error) {
+	return getAPIKeyEnv(account.APIKeyEnv, "opsgenie account", account.Name)
+}
+
+// GetWorkspaceAPIKey retrieves the API key of the Slack workspace from the
+// environment variable configured in the workspace.
+func GetWorkspaceAPIKey(workspace Workspace) (string, error) {
+	return getAPIKeyEnv(workspace.APIKeyEnv, "workspace", workspace.Name)
+}
+
+func getAPIKeyEnv(env, kind, name string) (string, error) {
+	if env == "" {
+		return "", fmt.Errorf("api_key_env is not configured for %s: %s", kind, name)
+	}
+
+	apiKey := os.Getenv(env)
+	if apiKey == "" {
+		return "", fmt.Errorf("%s is not configured for %s: %s", env, kind, name)
+	}
+
+	return apiKey, nil
+}
+
 // IsExternalTrigger retrieves if the Slackduty is triggered from external trigger or not.
 // If it is configured to `true`, the Slackduty process will exits once it updates the Slack
 // usergroup. It will ignore the `group[].schedule`.
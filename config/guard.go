@@ -0,0 +1,10 @@
+package config
+
+// Guard configures the limits that hold back the sync of the group when the
+// members look wrong(e.g. the PagerDuty API returned an empty team).
+// The zero value of each field disables the limit.
+type Guard struct {
+	MinMembers     int     `yaml:"min_members"`
+	MaxMembers     int     `yaml:"max_members"`
+	MaxChangeRatio float64 `yaml:"max_change_ratio"`
+}
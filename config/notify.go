@@ -0,0 +1,8 @@
+package config
+
+// Notify configures the direct messages sent to the Slack users whose
+// membership of the usergroup(s) has changed by the sync.
+type Notify struct {
+	Added   bool `yaml:"added"`
+	Removed bool `yaml:"removed"`
+}
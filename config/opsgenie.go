@@ -0,0 +1,44 @@
+package config
+
+// Opsgenie is the Opsgenie members. The schedules resolve the users on call
+// at the execution time. Account refers the named Opsgenie account, the
+// default one is used if it is empty.
+type Opsgenie struct {
+	Account   string   `yaml:"account"`
+	Schedules []string `yaml:"schedules"`
+	Teams     []string `yaml:"teams"`
+	Users     []string `yaml:"users"`
+}
+
+// Opsgenies is a list of the Opsgenie members from one or more accounts.
+// A single one can be written without the list in the config.
+type Opsgenies []*Opsgenie
+
+// UnmarshalYAML accepts both a single Opsgenie members and the list of them.
+func (o *Opsgenies) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []*Opsgenie
+	if err := unmarshal(&list); err == nil {
+		*o = list
+		return nil
+	}
+
+	var single Opsgenie
+	if err := unmarshal(&single); err != nil {
+		return err
+	}
+
+	*o = Opsgenies{&single}
+	return nil
+}
+
+// OpsgenieAccount configures the Opsgenie API. The API key of the default
+// account is resolved from the secret reference APIKey(default
+// env:SLACKDUTY_OPSGENIE_API_KEY). The named accounts that the Opsgenie
+// members can refer resolve it from APIKey, or read it from the environment
+// variable APIKeyEnv. The URL is `https://api.opsgenie.com` if not configured.
+type OpsgenieAccount struct {
+	Name      string `yaml:"name"`
+	APIKey    string `yaml:"api_key"`
+	APIKeyEnv string `yaml:"api_key_env"`
+	URL       string `yaml:"url"`
+}
@@ -1,9 +1,96 @@
 package config
 
-// Pagerduty ...
+// Pagerduty resolves the members from the PagerDuty account.
+// TeamRoles filters the members of the teams and the services by the role
+// in the team(manager, responder or observer). UserRoles filters all users
+// by the account role and ExcludeInvited excludes the users who haven't
+// accepted the invitation.
 type Pagerduty struct {
-	Schedules []string `yaml:"schedules"`
-	Services  []string `yaml:"services"`
-	Teams     []string `yaml:"teams"`
-	Users     []string `yaml:"users"`
+	Account        string     `yaml:"account"`
+	ExcludeInvited bool       `yaml:"exclude_invited"`
+	Incidents      *Incidents `yaml:"incidents"`
+	Schedules      []Schedule `yaml:"schedules"`
+	Services       []string   `yaml:"services"`
+	TeamRoles      []string   `yaml:"team_roles"`
+	Teams          []string   `yaml:"teams"`
+	UserRoles      *UserRoles `yaml:"user_roles"`
+	Users          []string   `yaml:"users"`
+}
+
+// Incidents selects the triggered or acknowledged incidents. The assignees, the
+// acknowledgers and the requested responders of the incidents are the members.
+// The incidents can be filtered by the services, the urgencies(high or low)
+// and the priorities by the name(e.g. P1).
+type Incidents struct {
+	Priorities []string `yaml:"priorities"`
+	Services   []string `yaml:"services"`
+	Urgencies  []string `yaml:"urgencies"`
+}
+
+// UserRoles are the account roles of the PagerDuty users to include or
+// exclude(e.g. stakeholder).
+type UserRoles struct {
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
+// Schedule is a PagerDuty schedule selector. Mode `next` resolves the users
+// on call at the next handoff and At resolves the users on call at the offset
+// from the execution time(e.g. `+24h`). Layer selects the layer by the name,
+// `final` for the final schedule or `overrides` for the overrides only. All
+// users of the schedule are resolved if none of them is set.
+type Schedule struct {
+	Schedule string `yaml:"schedule"`
+	Mode     string `yaml:"mode"`
+	At       string `yaml:"at"`
+	Layer    string `yaml:"layer"`
+}
+
+// UnmarshalYAML accepts both the selector string and the schedule with the
+// options.
+func (s *Schedule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var selector string
+	if err := unmarshal(&selector); err == nil {
+		*s = Schedule{Schedule: selector}
+		return nil
+	}
+
+	type schedule Schedule
+	var sche schedule
+	if err := unmarshal(&sche); err != nil {
+		return err
+	}
+
+	*s = Schedule(sche)
+	return nil
+}
+
+// Pagerduties is a list of the PagerDuty members from one or more accounts.
+// A single one can be written without the list in the config.
+type Pagerduties []*Pagerduty
+
+// UnmarshalYAML accepts both a single PagerDuty members and the list of them.
+func (p *Pagerduties) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []*Pagerduty
+	if err := unmarshal(&list); err == nil {
+		*p = list
+		return nil
+	}
+
+	var single Pagerduty
+	if err := unmarshal(&single); err != nil {
+		return err
+	}
+
+	*p = Pagerduties{&single}
+	return nil
+}
+
+// Account is a named PagerDuty account that the PagerDuty members can refer.
+// The API key is resolved from the secret reference APIKey, or read from the
+// environment variable APIKeyEnv.
+type Account struct {
+	Name      string `yaml:"name"`
+	APIKey    string `yaml:"api_key"`
+	APIKeyEnv string `yaml:"api_key_env"`
 }
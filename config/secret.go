@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// APIKeys are the secret references of the API keys for the default
+// PagerDuty account and Slack workspace. The environment variables
+// SLACKDUTY_PAGERDUTY_API_KEY and SLACKDUTY_SLACK_API_KEY are used if not
+// configured.
+type APIKeys struct {
+	Pagerduty string `yaml:"pagerduty"`
+	Slack     string `yaml:"slack"`
+}
+
+// Secrets configures the secret providers other than the builtin ones.
+type Secrets struct {
+	Vault *Vault `yaml:"vault"`
+}
+
+// Vault configures the Vault compatible HTTP API as the secret provider.
+// The token is read from the environment variable TokenEnv(default VAULT_TOKEN).
+type Vault struct {
+	Address  string `yaml:"address"`
+	TokenEnv string `yaml:"token_env"`
+}
+
+var envRefPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// references returns the credential fields that are secret references.
+func (c *Config) references() []*string {
+	fields := []*string{}
+	if c.APIKeys != nil {
+		fields = append(fields, &c.APIKeys.Pagerduty, &c.APIKeys.Slack)
+	}
+
+	for i := range c.Accounts {
+		fields = append(fields, &c.Accounts[i].APIKey)
+	}
+
+	for i := range c.Workspaces {
+		fields = append(fields, &c.Workspaces[i].APIKey)
+	}
+
+	if c.Opsgenie != nil {
+		fields = append(fields, &c.Opsgenie.APIKey)
+	}
+
+	for i := range c.OpsgenieAccounts {
+		fields = append(fields, &c.OpsgenieAccounts[i].APIKey)
+	}
+
+	return fields
+}
+
+// credentials returns the credential fields of the config in which the
+// `${NAME}` references are replaced.
+func (c *Config) credentials() []*string {
+	fields := c.references()
+	if c.Alert != nil && c.Alert.Pagerduty != nil {
+		fields = append(fields, &c.Alert.Pagerduty.RoutingKey)
+	}
+
+	return fields
+}
+
+// expandCredentials replaces the `${NAME}` references in the credential
+// fields with the environment variables. They are replaced after the config
+// is parsed so that a parse error never has the secrets. A secret reference
+// field that is only `${NAME}` becomes `env:NAME` so that it is resolved to
+// the API key itself.
+func (c *Config) expandCredentials() error {
+	references := map[*string]bool{}
+	for _, field := range c.references() {
+		references[field] = true
+	}
+
+	for _, field := range c.credentials() {
+		v, err := expandEnv(*field)
+		if err != nil {
+			return err
+		}
+
+		if m := envRefPattern.FindStringSubmatch(*field); references[field] && m != nil && m[0] == *field {
+			v = "env:" + m[1]
+		}
+
+		*field = v
+	}
+
+	return nil
+}
+
+// expandEnv replaces the `${NAME}` references in the value with the
+// environment variables. It returns an error if the variable is not set so
+// that the config isn't loaded with an empty API key. The error only has the
+// name of the variable.
+func expandEnv(s string) (string, error) {
+	var err error
+	expanded := envRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		name := envRefPattern.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s referenced in the config is not set", name)
+		}
+
+		return v
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return expanded, nil
+}
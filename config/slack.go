@@ -2,3 +2,28 @@ package config
 
 // Slack ...
 type Slack []string
+
+// Workspace is a named Slack workspace that groups can synchronize.
+// The API key is resolved from the secret reference APIKey, or read from the
+// environment variable APIKeyEnv.
+// TeamID should be set for the workspace-scoped usergroups when the API
+// key is an Enterprise Grid organization token.
+type Workspace struct {
+	Name      string `yaml:"name"`
+	APIKey    string `yaml:"api_key"`
+	APIKeyEnv string `yaml:"api_key_env"`
+	TeamID    string `yaml:"team_id"`
+}
+
+// ExcludeAccounts excludes the Slack users by the account attributes.
+// Guests are the single-channel and multi-channel guests. OtherTeams
+// excludes the users whose team is not the team of the workspace.
+// Deactivated users are excluded unless it is false because Slack rejects
+// them as the members of the usergroups.
+type ExcludeAccounts struct {
+	Bots                bool  `yaml:"bots"`
+	Deactivated         *bool `yaml:"deactivated"`
+	MultiChannelGuests  bool  `yaml:"multi_channel_guests"`
+	OtherTeams          bool  `yaml:"other_teams"`
+	SingleChannelGuests bool  `yaml:"single_channel_guests"`
+}
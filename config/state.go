@@ -0,0 +1,10 @@
+package config
+
+// State configures the backend that records the history of the syncs.
+// Only the `file` type is supported now. The history is trimmed to
+// HistoryLimit records per group.
+type State struct {
+	Type         string `yaml:"type"`
+	Path         string `yaml:"path"`
+	HistoryLimit int    `yaml:"history_limit"`
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+var timeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ParseTime parses the time in the config, the roster files and the command
+// line. It accepts RFC3339, the time without the timezone and the date. The
+// ones without the timezone are parsed in the location. It reports whether
+// the value is the date.
+func ParseTime(v string, loc *time.Location) (time.Time, bool, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, v, loc); err == nil {
+			return t, false, nil
+		}
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", v, loc); err == nil {
+		return t, true, nil
+	}
+
+	return time.Time{}, false, fmt.Errorf("time %s is invalid, must be RFC3339, 2006-01-02T15:04 or 2006-01-02", v)
+}
@@ -0,0 +1,12 @@
+package config
+
+// Unavailable selects the users who are unavailable at the execution time.
+// The users covered by the override of someone else on the Schedules of the
+// PagerDuty Account, and the users in the time-off Calendars and Files are
+// unavailable.
+type Unavailable struct {
+	Account   string     `yaml:"account"`
+	Calendars []Calendar `yaml:"calendars"`
+	Files     []string   `yaml:"files"`
+	Schedules []string   `yaml:"schedules"`
+}
@@ -0,0 +1,29 @@
+package config
+
+// Window is a recurring time window(e.g. Mon-Fri 09:00-18:00 in Asia/Tokyo).
+// Days are the weekdays or the ranges of them(e.g. `mon-fri`, `sat`) and
+// every day is in the window if they are empty. Hours is the range of the
+// time of the day(e.g. `09:00-18:00`, `22:00-06:00`) and the whole day is in
+// the window if it is empty. Timezone is the IANA name and the local timezone
+// is used if it is empty. The events of the holidays calendar(ICS file) are
+// out of the window.
+type Window struct {
+	Days     []string `yaml:"days"`
+	Holidays string   `yaml:"holidays"`
+	Hours    string   `yaml:"hours"`
+	Timezone string   `yaml:"timezone"`
+}
+
+// Rule selects the members of the group while the time is in the window.
+type Rule struct {
+	Name    string   `yaml:"name"`
+	Window  *Window  `yaml:"window"`
+	Members *Members `yaml:"members"`
+}
+
+// Disable configures when the usergroups of the group are disabled by the
+// sync. They are enabled again when the sync updates them.
+type Disable struct {
+	Empty   bool    `yaml:"empty"`
+	Outside *Window `yaml:"outside"`
+}
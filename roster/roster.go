@@ -0,0 +1,190 @@
+// Package roster parses the static rosters of the members. A roster is a CSV
+// file with the header or a YAML file of the list of the entries.
+package roster
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/config"
+	"gopkg.in/yaml.v2"
+)
+
+// Entry is a member of the roster. Either the email or the Slack ID is
+// required. The entry is active between From and Until, Until is exclusive.
+// Zero From or Until is unbounded.
+type Entry struct {
+	Email   string
+	SlackID string
+	From    time.Time
+	Until   time.Time
+}
+
+// Active reports whether the entry is active at the time.
+func (e Entry) Active(t time.Time) bool {
+	if !e.From.IsZero() && t.Before(e.From) {
+		return false
+	}
+
+	if !e.Until.IsZero() && !t.Before(e.Until) {
+		return false
+	}
+
+	return true
+}
+
+// Load parses the roster file by the extension(.csv, .yml or .yaml). The
+// dates and the times without the timezone are parsed in the location.
+func Load(path string, loc *time.Location) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		entries, err = ParseCSV(f, loc)
+	case ".yml", ".yaml":
+		entries, err = ParseYAML(f, loc)
+	default:
+		return nil, fmt.Errorf("roster extension %s is invalid, must be .csv, .yml or .yaml path: %s", ext, path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the roster path: %s error: %v", path, err)
+	}
+
+	return entries, nil
+}
+
+// ParseCSV parses the CSV roster. The first row is the header of the columns
+// email, slack_id, from and until. Only email or slack_id is required.
+func ParseCSV(r io.Reader, loc *time.Location) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "email", "slack_id", "from", "until":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("column %s is invalid, must be email, slack_id, from or until", name)
+		}
+	}
+
+	if _, ok := columns["email"]; !ok {
+		if _, ok := columns["slack_id"]; !ok {
+			return nil, fmt.Errorf("header must have email or slack_id")
+		}
+	}
+
+	entries := []Entry{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		entry, err := newEntry(field("email"), field("slack_id"), field("from"), field("until"), loc)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+type yamlEntry struct {
+	Email   string `yaml:"email"`
+	SlackID string `yaml:"slack_id"`
+	From    string `yaml:"from"`
+	Until   string `yaml:"until"`
+}
+
+// ParseYAML parses the YAML roster of the list of the entries with email,
+// slack_id, from and until.
+func ParseYAML(r io.Reader, loc *time.Location) ([]Entry, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []yamlEntry
+	if err := yaml.UnmarshalStrict(b, &list); err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for i, e := range list {
+		entry, err := newEntry(e.Email, e.SlackID, e.From, e.Until, loc)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %v", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func newEntry(email, slackID, from, until string, loc *time.Location) (Entry, error) {
+	if email == "" && slackID == "" {
+		return Entry{}, fmt.Errorf("entry must have email or slack_id")
+	}
+
+	entry := Entry{Email: email, SlackID: slackID}
+	if from != "" {
+		t, _, err := config.ParseTime(from, loc)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.From = t
+	}
+
+	if until != "" {
+		t, date, err := config.ParseTime(until, loc)
+		if err != nil {
+			return Entry{}, err
+		}
+
+		// Note: The date of until is inclusive(e.g. `until: 2020-06-30`
+		// is active through the day).
+		if date {
+			t = t.AddDate(0, 0, 1)
+		}
+		entry.Until = t
+	}
+
+	if !entry.From.IsZero() && !entry.Until.IsZero() && !entry.From.Before(entry.Until) {
+		return Entry{}, fmt.Errorf("from %s must be before until %s", from, until)
+	}
+
+	return entry, nil
+}
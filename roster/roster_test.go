@@ -0,0 +1,110 @@
+package roster
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSV(t *testing.T) {
+	tcs := map[string]struct {
+		csv     string
+		want    []Entry
+		success bool
+	}{
+		"email and slack id": {"email,slack_id\nalice@example.com,\n,U2\n", []Entry{{Email: "alice@example.com"}, {SlackID: "U2"}}, true},
+		"window": {
+			"email,from,until\nalice@example.com,2020-06-01T09:00,2020-06-30\n",
+			[]Entry{{Email: "alice@example.com", From: time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC), Until: time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)}},
+			true,
+		},
+		"comment":             {"# nightly export\nemail\nalice@example.com\n", []Entry{{Email: "alice@example.com"}}, true},
+		"empty":               {"", []Entry{}, true},
+		"unknown column":      {"name,email\nalice,alice@example.com\n", nil, false},
+		"no email or slack":   {"from\n2020-06-01\n", nil, false},
+		"empty entry":         {"email,slack_id\n,\n", nil, false},
+		"invalid time":        {"email,from\nalice@example.com,tomorrow\n", nil, false},
+		"until before from":   {"email,from,until\nalice@example.com,2020-06-02,2020-06-01T00:00\n", nil, false},
+		"rfc3339 with offset": {"email,until\nalice@example.com,2020-06-01T09:00:00+09:00\n", []Entry{{Email: "alice@example.com", Until: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}}, true},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := ParseCSV(strings.NewReader(tc.csv), time.UTC)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if tc.success && !equalEntries(got, tc.want) {
+				t.Fatalf("entries don't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseYAML(t *testing.T) {
+	tcs := map[string]struct {
+		yaml    string
+		want    []Entry
+		success bool
+	}{
+		"entries": {
+			"- email: alice@example.com\n  until: 2020-06-30\n- slack_id: U2\n",
+			[]Entry{{Email: "alice@example.com", Until: time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)}, {SlackID: "U2"}},
+			true,
+		},
+		"unknown field": {"- name: alice\n", nil, false},
+		"empty entry":   {"- from: 2020-06-01\n", nil, false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := ParseYAML(strings.NewReader(tc.yaml), time.UTC)
+			if (err == nil) != tc.success {
+				t.Fatalf("test %s unexpected error: %v", n, err)
+			}
+
+			if tc.success && !equalEntries(got, tc.want) {
+				t.Fatalf("entries don't match got: %v want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEntry_Active(t *testing.T) {
+	entry := Entry{From: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), Until: time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)}
+	tcs := map[string]struct {
+		entry Entry
+		t     time.Time
+		want  bool
+	}{
+		"unbounded":    {Entry{}, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), true},
+		"before from":  {entry, time.Date(2020, 5, 31, 23, 59, 0, 0, time.UTC), false},
+		"at from":      {entry, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), true},
+		"before until": {entry, time.Date(2020, 6, 30, 23, 59, 0, 0, time.UTC), true},
+		"at until":     {entry, time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if got := tc.entry.Active(tc.t); got != tc.want {
+				t.Fatalf("test %s doesn't match got: %v want: %v", n, got, tc.want)
+			}
+		})
+	}
+}
+
+// equalEntries compares the entries by the instant of the times.
+func equalEntries(got, want []Entry) bool {
+	if len(got) != len(want) {
+		return false
+	}
+
+	for i := range got {
+		if got[i].Email != want[i].Email || got[i].SlackID != want[i].SlackID || !got[i].From.Equal(want[i].From) || !got[i].Until.Equal(want[i].Until) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,23 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+)
+
+type envProvider struct{}
+
+// NewEnvProvider creates a provider that reads the secret from the
+// environment variable.
+func NewEnvProvider() Provider {
+	return &envProvider{}
+}
+
+func (p *envProvider) Get(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%s is not configured", name)
+	}
+
+	return v, nil
+}
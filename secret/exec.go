@@ -0,0 +1,47 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const defaultExecTimeout = 30 * time.Second
+
+type execProvider struct {
+	timeout time.Duration
+}
+
+// NewExecProvider creates a provider that runs the command and reads the
+// secret from the stdout. The command is split by spaces, shell syntax is not
+// supported.
+func NewExecProvider() Provider {
+	return &execProvider{
+		timeout: defaultExecTimeout,
+	}
+}
+
+// Get runs the command. The stdout and stderr are not included in the error
+// because they might have the secret.
+func (p *execProvider) Get(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", errors.New("command is empty")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("command %s failed: %v", args[0], err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
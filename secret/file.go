@@ -0,0 +1,24 @@
+package secret
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+type fileProvider struct{}
+
+// NewFileProvider creates a provider that reads the secret from the file
+// (e.g. Kubernetes secret volume). Leading and trailing whitespaces are
+// trimmed.
+func NewFileProvider() Provider {
+	return &fileProvider{}
+}
+
+func (p *fileProvider) Get(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,88 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Provider is a interface that the secret provider should implement.
+// Get resolves the value of the reference without the kind prefix.
+//
+// Implementations must not include the secret value in the error.
+type Provider interface {
+	Get(string) (string, error)
+}
+
+// Resolver resolves the secret references in `kind:value` format
+// (e.g. `file:/var/run/secrets/slack-api-key`) by the provider of the kind.
+type Resolver struct {
+	mux       sync.RWMutex
+	providers map[string]Provider
+}
+
+type resolverOptions struct {
+	providers map[string]Provider
+}
+
+// ResolverOption configures the resolver
+type ResolverOption func(*resolverOptions)
+
+// WithProvider registers the provider for the kind. It overrides the
+// builtin provider if the kind is the same.
+func WithProvider(kind string, provider Provider) ResolverOption {
+	return func(o *resolverOptions) {
+		o.providers[kind] = provider
+	}
+}
+
+// NewResolver creates a resolver with the builtin `env`, `file` and `exec`
+// providers.
+func NewResolver(opts ...ResolverOption) *Resolver {
+	o := resolverOptions{
+		providers: map[string]Provider{
+			"env":  NewEnvProvider(),
+			"file": NewFileProvider(),
+			"exec": NewExecProvider(),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Resolver{
+		providers: o.providers,
+	}
+}
+
+// Resolve resolves the secret reference. The reference itself is not included
+// in the error when it is malformed because it might be a raw secret.
+func (r *Resolver) Resolve(ref string) (string, error) {
+	s := strings.SplitN(ref, ":", 2)
+	if len(s) != 2 || s[1] == "" {
+		return "", errors.New("secret reference is specified in wrong format, must be kind:value")
+	}
+
+	kind := s[0]
+	val := s[1]
+
+	r.mux.RLock()
+	provider, ok := r.providers[kind]
+	r.mux.RUnlock()
+	if !ok {
+		return "", errors.New("secret reference kind is not supported, must be one of the registered providers")
+	}
+
+	secret, err := provider.Get(val)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret: %v", kind, err)
+	}
+
+	if secret == "" {
+		return "", fmt.Errorf("%s secret is empty", kind)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,77 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultVaultTimeout = 10 * time.Second
+
+type vaultProvider struct {
+	address string
+	token   string
+	client  *http.Client
+}
+
+// NewVaultProvider creates a provider that reads the secret from the Vault
+// compatible HTTP API. The reference is `path#key`(e.g.
+// `secret/data/slackduty#slack-api-key`). Both KV version 1 and 2 are
+// supported.
+func NewVaultProvider(address, token string) Provider {
+	return &vaultProvider{
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+		client:  &http.Client{Timeout: defaultVaultTimeout},
+	}
+}
+
+type vaultResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+func (p *vaultProvider) Get(ref string) (string, error) {
+	s := strings.Split(ref, "#")
+	if len(s) != 2 {
+		return "", fmt.Errorf("vault reference is specified in wrong format, must be path#key: %s", ref)
+	}
+
+	path := strings.TrimPrefix(s[0], "/")
+	key := s[1]
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/%s", p.address, path), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status code %d for path: %s", resp.StatusCode, path)
+	}
+
+	var body vaultResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response for path: %s", path)
+	}
+
+	data := body.Data
+	// Note: KV version 2 nests the secret data in data.data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	v, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("key %s doesn't exist in path: %s", key, path)
+	}
+
+	return v, nil
+}
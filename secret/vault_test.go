@@ -0,0 +1,58 @@
+package secret
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/slackduty":
+			w.Write([]byte(`{"data":{"data":{"slack":"kv2-secret"},"metadata":{"version":1}}}`))
+		case "/v1/kv/slackduty":
+			w.Write([]byte(`{"data":{"slack":"kv1-secret"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tcs := map[string]struct {
+		token   string
+		ref     string
+		want    string
+		success bool
+	}{
+		"kv version 2":   {"test-token", "secret/data/slackduty#slack", "kv2-secret", true},
+		"kv version 1":   {"test-token", "kv/slackduty#slack", "kv1-secret", true},
+		"key not exists": {"test-token", "kv/slackduty#pagerduty", "", false},
+		"path not found": {"test-token", "kv/unknown#slack", "", false},
+		"wrong format":   {"test-token", "kv/slackduty", "", false},
+		"forbidden":      {"wrong-token", "kv/slackduty#slack", "", false},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			provider := NewVaultProvider(server.URL+"/", tc.token)
+			got, err := provider.Get(tc.ref)
+			if err != nil {
+				if tc.success {
+					t.Fatalf("test %s error: %v", n, err)
+				} else {
+					return
+				}
+			}
+
+			if got != tc.want {
+				t.Fatalf("secret doesn't match got: %s want: %s", got, tc.want)
+			}
+		})
+	}
+}
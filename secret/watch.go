@@ -0,0 +1,51 @@
+package secret
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Watch re-resolves the `file` secret references every interval until the
+// context is done and calls fn with the reference whose value has changed.
+// Other kinds are resolved only once because they are not expected to change
+// on the disk(e.g. Kubernetes secret volume).
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration, refs []string, fn func(ref, value string), errFn func(ref string, err error)) {
+	values := map[string]string{}
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref, "file:") {
+			continue
+		}
+
+		// Note: The reference is watched even if the file doesn't exist yet
+		// so that the secret mounted later is picked up.
+		v, err := r.Resolve(ref)
+		if err != nil {
+			errFn(ref, err)
+		}
+		values[ref] = v
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for ref, old := range values {
+				v, err := r.Resolve(ref)
+				if err != nil {
+					errFn(ref, err)
+					continue
+				}
+
+				if v != old {
+					values[ref] = v
+					fn(ref, v)
+				}
+			}
+		}
+	}
+}
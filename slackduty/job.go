@@ -72,17 +72,13 @@ func NewJob(fn func() error, opts ...JobOption) Job {
 // Run a cronjob based on cron schedule.
 // To cancle a job, pass the a context.
 func (cj *cronJob) Run(ctx context.Context) error {
-	errChan := make(chan error, 1)
-	go func() {
-		cj.cron.AddFunc(cj.schedule, func() {
-			errChan <- cj.fn()
-		})
-	}()
-
-	for {
-
+	if err := cj.cron.AddFunc(cj.schedule, func() {
+		cj.fn()
+	}); err != nil {
+		return err
 	}
 
+	<-ctx.Done()
 	return nil
 }
 
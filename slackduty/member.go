@@ -4,48 +4,243 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Members is a struct for managing the Members from
 // various PagerDuty resources(e.g. teams, services, schedules).
+// Removed is the members that are resolved but removed afterwards(e.g. by
+// the exclude).
 type Members struct {
-	mux     sync.RWMutex
-	Members []Member
+	mux       sync.RWMutex
+	Members   []Member
+	Removed   []Member
+	Breakdown map[string]int
 }
 
 // Member represents a single member(Slack user).
-// Currently, we use ID and Email only.
+// Sources are the selectors that the member is resolved from(e.g.
+// `pagerduty.schedule/name:web-oncall`). They answer why the user is a member.
+// Until is the end of the shift if the sources know it, zero if unknown.
+// RemovedBy is the step that removed the member(e.g. `exclude`), empty if the
+// member is not removed.
 type Member struct {
-	ID    string
-	Email string
+	ID        string    `json:"id"`
+	Email     string    `json:"email,omitempty"`
+	Sources   []string  `json:"sources,omitempty"`
+	Until     time.Time `json:"-"`
+	RemovedBy string    `json:"-"`
 }
 
 // Add appends a member to the Members struct.
-// It will also removes the duplication of the Slack ID.
+// It will also removes the duplication of the Slack ID. The sources of the
+// duplicated member are merged.
 func (m *Members) Add(member *Member) {
 	m.mux.Lock()
 	var exists bool
-	for _, m := range m.Members {
-		if m.ID == member.ID {
+	for i := range m.Members {
+		if m.Members[i].ID == member.ID {
 			exists = true
+			m.Members[i].Sources = mergeSources(m.Members[i].Sources, member.Sources)
+			if m.Members[i].Email == "" {
+				m.Members[i].Email = member.Email
+			}
+			m.Members[i].Until = latestUntil(m.Members[i].Until, member.Until)
 		}
 	}
 
 	if !exists {
-		m.Members = append(m.Members, *member)
+		added := *member
+		added.Sources = mergeSources(nil, member.Sources)
+		m.Members = append(m.Members, added)
+	}
+	m.mux.Unlock()
+}
+
+// AddFrom appends a member resolved from the source and counts the member
+// for the source in the breakdown. A member resolved from several sources is
+// counted in each of them.
+func (m *Members) AddFrom(source string, member *Member) {
+	m.mux.Lock()
+	if m.Breakdown == nil {
+		m.Breakdown = map[string]int{}
+	}
+	m.Breakdown[source]++
+	m.mux.Unlock()
+
+	added := *member
+	added.Sources = mergeSources(member.Sources, []string{source})
+	m.Add(&added)
+}
+
+// mergeSources returns the sources of both without the duplication.
+func mergeSources(sources, others []string) []string {
+	result := append([]string{}, sources...)
+	for _, other := range others {
+		var exists bool
+		for _, source := range result {
+			if source == other {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			result = append(result, other)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+// latestUntil returns the end of the shift of the member resolved from both
+// of the sources. It is unknown if either is unknown.
+func latestUntil(until, other time.Time) time.Time {
+	if until.IsZero() || other.IsZero() {
+		return time.Time{}
+	}
+
+	if other.After(until) {
+		return other
+	}
+
+	return until
+}
+
+// earliestUntil returns the end of the shift of the member that must be in
+// both of the sources.
+func earliestUntil(until, other time.Time) time.Time {
+	if until.IsZero() || (!other.IsZero() && other.Before(until)) {
+		return other
+	}
+
+	return until
+}
+
+// Remove removes the member by the Slack ID.
+func (m *Members) Remove(id string) {
+	m.mux.Lock()
+	members := []Member{}
+	for _, member := range m.Members {
+		if member.ID != id {
+			members = append(members, member)
+		}
+	}
+	m.Members = members
+	m.mux.Unlock()
+}
+
+// RemoveBy removes the member by the Slack ID and keeps it in the removed
+// members with the step that removed it. It answers why a resolved user is
+// not a member.
+func (m *Members) RemoveBy(id, step string) {
+	m.mux.Lock()
+	members := []Member{}
+	for _, member := range m.Members {
+		if member.ID != id {
+			members = append(members, member)
+			continue
+		}
+
+		member.RemovedBy = step
+		m.Removed = append(m.Removed, member)
 	}
+	m.Members = members
 	m.mux.Unlock()
 }
 
+// Union returns the members in either of the members.
+func (m *Members) Union(other *Members) *Members {
+	result := m.copy(other)
+	for _, member := range m.list() {
+		member := member
+		result.Add(&member)
+	}
+
+	for _, member := range other.list() {
+		member := member
+		result.Add(&member)
+	}
+
+	return result
+}
+
+// Intersect returns the members in both of the members. The sources of the
+// both are kept.
+func (m *Members) Intersect(other *Members) *Members {
+	result := m.copy(other)
+	members := other.byID()
+	for _, member := range m.list() {
+		member := member
+		if o, ok := members[member.ID]; ok {
+			member.Sources = mergeSources(member.Sources, o.Sources)
+			member.Until = earliestUntil(member.Until, o.Until)
+			result.Add(&member)
+		}
+	}
+
+	return result
+}
+
+// Subtract returns the members that are not in the other members.
+func (m *Members) Subtract(other *Members) *Members {
+	result := m.copy(other)
+	members := other.byID()
+	for _, member := range m.list() {
+		member := member
+		if _, ok := members[member.ID]; !ok {
+			result.Add(&member)
+		}
+	}
+
+	return result
+}
+
+// copy returns empty members with the breakdown of both of the members so
+// that the result of the set operation keeps where the members are resolved
+// from.
+func (m *Members) copy(other *Members) *Members {
+	result := &Members{Breakdown: map[string]int{}}
+	for _, members := range []*Members{m, other} {
+		members.mux.RLock()
+		for source, count := range members.Breakdown {
+			result.Breakdown[source] += count
+		}
+		members.mux.RUnlock()
+	}
+
+	return result
+}
+
+func (m *Members) list() []Member {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	return append([]Member{}, m.Members...)
+}
+
+func (m *Members) byID() map[string]Member {
+	members := map[string]Member{}
+	for _, member := range m.list() {
+		members[member.ID] = member
+	}
+
+	return members
+}
+
 // Filter removes the excluded Slack users by ID or Email.
 func (m *Members) Filter(blacklists []string) (*Members, error) {
-	newMembers := &Members{}
+	newMembers := &Members{Breakdown: m.Breakdown, Removed: m.Removed}
 	if len(blacklists) == 0 {
 		newMembers.Members = m.Members
 		return newMembers, nil
 	}
 
 	for _, member := range m.Members {
+		var excluded bool
 		for _, blacklist := range blacklists {
 			s := strings.Split(blacklist, ":")
 			if len(s) != 2 {
@@ -57,17 +252,17 @@ func (m *Members) Filter(blacklists []string) (*Members, error) {
 
 			switch kind {
 			case "id":
-				if member.ID != val {
-					newMembers.Members = append(newMembers.Members, member)
-				}
+				excluded = excluded || member.ID == val
 			case "email":
-				if member.Email != val {
-					newMembers.Members = append(newMembers.Members, member)
-				}
+				excluded = excluded || (member.Email != "" && member.Email == val)
 			default:
 				return nil, fmt.Errorf("blacklist format should be id or email blacklist: %s", blacklist)
 			}
 		}
+
+		if !excluded {
+			newMembers.Members = append(newMembers.Members, member)
+		}
 	}
 
 	return newMembers, nil
@@ -89,3 +284,29 @@ func FlattenMembers(members []Member) string {
 
 	return result
 }
+
+// Diff compares the Slack IDs currently in the usergroup with the members
+// that the usergroup should have. It returns the IDs that will be newly added
+// and the IDs that will be removed by the update.
+func Diff(current []string, members []Member) (added, removed []string) {
+	currentIDs := map[string]bool{}
+	for _, id := range current {
+		currentIDs[id] = true
+	}
+
+	memberIDs := map[string]bool{}
+	for _, member := range members {
+		memberIDs[member.ID] = true
+		if !currentIDs[member.ID] {
+			added = append(added, member.ID)
+		}
+	}
+
+	for _, id := range current {
+		if !memberIDs[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	return added, removed
+}
@@ -1,8 +1,10 @@
 package slackduty
 
 import (
+	"reflect"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestAdd(t *testing.T) {
@@ -10,9 +12,9 @@ func TestAdd(t *testing.T) {
 		members    []Member
 		duplicated int
 	}{
-		"single member":             {[]Member{{"id1", "id1@example.com"}}, 0},
-		"mutiple unique member":     {[]Member{{"id1", "id1@example.com"}, {"id2", "id2@example.com"}}, 0},
-		"mutiple deplicated member": {[]Member{{"id1", "id1@example.com"}, {"id1", "id1@example.com"}}, 1},
+		"single member":             {[]Member{{ID: "id1", Email: "id1@example.com"}}, 0},
+		"mutiple unique member":     {[]Member{{ID: "id1", Email: "id1@example.com"}, {ID: "id2", Email: "id2@example.com"}}, 0},
+		"mutiple deplicated member": {[]Member{{ID: "id1", Email: "id1@example.com"}, {ID: "id1", Email: "id1@example.com"}}, 1},
 	}
 
 	for n, tc := range tcs {
@@ -43,11 +45,13 @@ func TestFilter(t *testing.T) {
 		want      []Member
 		success   bool
 	}{
-		"no blacklist":           {[]Member{{"id1", "id1@example.com"}}, []string{}, []Member{{"id1", "id1@example.com"}}, true},
-		"single ID blacklist":    {[]Member{{"id1", "id1@example.com"}}, []string{"id:id1"}, []Member{}, true},
-		"single Email blacklist": {[]Member{{"id1", "id1@example.com"}}, []string{"email:id1@example.com"}, []Member{}, true},
-		"no matching blacklist":  {[]Member{{"id1", "id1@example.com"}}, []string{"id:idX"}, []Member{{"id1", "id1@example.com"}}, true},
-		"wrong blacklist format": {[]Member{{"id1", "id1@example.com"}}, []string{"wrong:wrong"}, []Member{{"id1", "id1@example.com"}}, false},
+		"no blacklist":           {[]Member{{ID: "id1", Email: "id1@example.com"}}, []string{}, []Member{{ID: "id1", Email: "id1@example.com"}}, true},
+		"single ID blacklist":    {[]Member{{ID: "id1", Email: "id1@example.com"}}, []string{"id:id1"}, []Member{}, true},
+		"single Email blacklist": {[]Member{{ID: "id1", Email: "id1@example.com"}}, []string{"email:id1@example.com"}, []Member{}, true},
+		"no matching blacklist":  {[]Member{{ID: "id1", Email: "id1@example.com"}}, []string{"id:idX"}, []Member{{ID: "id1", Email: "id1@example.com"}}, true},
+		"multiple blacklists":    {[]Member{{ID: "id1", Email: "id1@example.com"}, {ID: "id2", Email: "id2@example.com"}}, []string{"id:idX", "email:id2@example.com"}, []Member{{ID: "id1", Email: "id1@example.com"}}, true},
+		"first of blacklists":    {[]Member{{ID: "id1", Email: "id1@example.com"}}, []string{"id:id1", "id:idX"}, []Member{}, true},
+		"wrong blacklist format": {[]Member{{ID: "id1", Email: "id1@example.com"}}, []string{"wrong:wrong"}, []Member{{ID: "id1", Email: "id1@example.com"}}, false},
 	}
 
 	for n, tc := range tcs {
@@ -65,6 +69,12 @@ func TestFilter(t *testing.T) {
 			if len(r.Members) != len(tc.want) {
 				t.Fatalf("filter result is unexpected got: %d, want: %d", len(r.Members), len(tc.want))
 			}
+
+			for i := range tc.want {
+				if r.Members[i].ID != tc.want[i].ID {
+					t.Fatalf("filter result is unexpected got: %v, want: %v", r.Members, tc.want)
+				}
+			}
 		})
 	}
 }
@@ -74,8 +84,8 @@ func TestFlattenMembers(t *testing.T) {
 		members []Member
 		want    string
 	}{
-		"single member":    {[]Member{{"id1", "id1@example.com"}}, "id1"},
-		"multiple members": {[]Member{{"id1", "id1@example.com"}, {"id2", "id2@example.com"}, {"id3", "id3@example.com"}}, "id1,id2,id3"},
+		"single member":    {[]Member{{ID: "id1", Email: "id1@example.com"}}, "id1"},
+		"multiple members": {[]Member{{ID: "id1", Email: "id1@example.com"}, {ID: "id2", Email: "id2@example.com"}, {ID: "id3", Email: "id3@example.com"}}, "id1,id2,id3"},
 	}
 
 	for n, tc := range tcs {
@@ -87,3 +97,99 @@ func TestFlattenMembers(t *testing.T) {
 		})
 	}
 }
+
+func TestDiff(t *testing.T) {
+	tcs := map[string]struct {
+		current []string
+		members []Member
+		added   []string
+		removed []string
+	}{
+		"no change":       {[]string{"id1"}, []Member{{ID: "id1", Email: "id1@example.com"}}, nil, nil},
+		"added member":    {[]string{"id1"}, []Member{{ID: "id1", Email: "id1@example.com"}, {ID: "id2", Email: "id2@example.com"}}, []string{"id2"}, nil},
+		"removed member":  {[]string{"id1", "id2"}, []Member{{ID: "id2", Email: "id2@example.com"}}, nil, []string{"id1"}},
+		"replaced member": {[]string{"id1"}, []Member{{ID: "id2", Email: "id2@example.com"}}, []string{"id2"}, []string{"id1"}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			added, removed := Diff(tc.current, tc.members)
+			if !reflect.DeepEqual(added, tc.added) {
+				t.Fatalf("added doesn't match got: %v want: %v", added, tc.added)
+			}
+
+			if !reflect.DeepEqual(removed, tc.removed) {
+				t.Fatalf("removed doesn't match got: %v want: %v", removed, tc.removed)
+			}
+		})
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	web := &Members{Members: []Member{{ID: "id1", Email: "id1@example.com"}, {ID: "id2", Email: "id2@example.com"}}, Breakdown: map[string]int{"team/web": 2}}
+	oncall := &Members{Members: []Member{{ID: "id2", Email: "id2@example.com"}, {ID: "id3", Email: "id3@example.com"}}, Breakdown: map[string]int{"schedule/web": 2}}
+
+	tcs := map[string]struct {
+		got  *Members
+		want []Member
+	}{
+		"union":     {web.Union(oncall), []Member{{ID: "id1", Email: "id1@example.com"}, {ID: "id2", Email: "id2@example.com"}, {ID: "id3", Email: "id3@example.com"}}},
+		"intersect": {web.Intersect(oncall), []Member{{ID: "id2", Email: "id2@example.com"}}},
+		"subtract":  {web.Subtract(oncall), []Member{{ID: "id1", Email: "id1@example.com"}}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.got.Members, tc.want) {
+				t.Fatalf("members don't match got: %v want: %v", tc.got.Members, tc.want)
+			}
+
+			want := map[string]int{"team/web": 2, "schedule/web": 2}
+			if !reflect.DeepEqual(tc.got.Breakdown, want) {
+				t.Fatalf("breakdown doesn't match got: %v want: %v", tc.got.Breakdown, want)
+			}
+		})
+	}
+}
+
+func TestAddFrom_Sources(t *testing.T) {
+	members := &Members{}
+	members.AddFrom("pagerduty.team/name:web", &Member{ID: "id1"})
+	members.AddFrom("pagerduty.schedule/name:web-oncall", &Member{ID: "id1", Email: "id1@example.com"})
+	members.AddFrom("pagerduty.team/name:web", &Member{ID: "id1"})
+
+	want := []Member{{ID: "id1", Email: "id1@example.com", Sources: []string{"pagerduty.team/name:web", "pagerduty.schedule/name:web-oncall"}}}
+	if !reflect.DeepEqual(members.Members, want) {
+		t.Fatalf("members don't match got: %v want: %v", members.Members, want)
+	}
+
+	other := &Members{}
+	other.AddFrom("slack/id:id1", &Member{ID: "id1"})
+	got := members.Intersect(other).Members[0].Sources
+	if wantSources := []string{"pagerduty.team/name:web", "pagerduty.schedule/name:web-oncall", "slack/id:id1"}; !reflect.DeepEqual(got, wantSources) {
+		t.Fatalf("sources don't match got: %v want: %v", got, wantSources)
+	}
+}
+
+func TestAdd_Until(t *testing.T) {
+	early := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+
+	members := &Members{}
+	members.Add(&Member{ID: "id1", Until: early})
+	members.Add(&Member{ID: "id1", Until: late})
+	if got := members.Members[0].Until; !got.Equal(late) {
+		t.Fatalf("until doesn't match got: %v want: %v", got, late)
+	}
+
+	members.Add(&Member{ID: "id1"})
+	if got := members.Members[0].Until; !got.IsZero() {
+		t.Fatalf("until must be unknown got: %v", got)
+	}
+
+	other := &Members{}
+	other.Add(&Member{ID: "id1", Until: early})
+	if got := members.Intersect(other).Members[0].Until; !got.Equal(early) {
+		t.Fatalf("until doesn't match got: %v want: %v", got, early)
+	}
+}
@@ -0,0 +1,296 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultHistoryLimit = 1000
+
+var _ Store = (*fileStore)(nil)
+
+type fileStore struct {
+	mux   sync.Mutex
+	path  string
+	limit int
+}
+
+// fileState is the content of the state file.
+type fileState struct {
+	Records   []Record         `json:"records"`
+	Paused    map[string]Pause `json:"paused,omitempty"`
+	Overrides []Override       `json:"overrides,omitempty"`
+}
+
+// NewFileStore creates a store that keeps the state in a local JSON file.
+// The history is trimmed to the limit per group, the default is used if the
+// limit is not positive.
+func NewFileStore(path string, limit int) (Store, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	return &fileStore{
+		path:  path,
+		limit: limit,
+	}, nil
+}
+
+func (s *fileStore) Save(record Record) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	unlock, err := lockFile(s.lockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	state.Records = append(state.Records, record)
+	state.Records = trim(state.Records, s.limit)
+	return s.write(state)
+}
+
+func (s *fileStore) History(key string) ([]Record, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	records := []Record{}
+	for _, record := range state.Records {
+		if Match(record, key) {
+			records = append(records, record)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	return records, nil
+}
+
+func (s *fileStore) Pause(pause Pause) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	unlock, err := lockFile(s.lockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if state.Paused == nil {
+		state.Paused = map[string]Pause{}
+	}
+
+	state.Paused[pause.Group] = pause
+	return s.write(state)
+}
+
+func (s *fileStore) Resume(group string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	unlock, err := lockFile(s.lockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := state.Paused[group]; !ok {
+		return nil
+	}
+
+	delete(state.Paused, group)
+	return s.write(state)
+}
+
+func (s *fileStore) Paused(group string) (*Pause, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	pause, ok := state.Paused[group]
+	if !ok {
+		return nil, nil
+	}
+
+	return &pause, nil
+}
+
+func (s *fileStore) AddOverride(override Override, now time.Time) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	unlock, err := lockFile(s.lockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	state.Overrides = append(Active(state.Overrides, now), override)
+	return s.write(state)
+}
+
+func (s *fileStore) DeleteOverride(id string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	unlock, err := lockFile(s.lockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	overrides := []Override{}
+	for _, override := range state.Overrides {
+		if override.ID != id {
+			overrides = append(overrides, override)
+		}
+	}
+
+	if len(overrides) == len(state.Overrides) {
+		return fmt.Errorf("override is not found id: %s", id)
+	}
+
+	state.Overrides = overrides
+	return s.write(state)
+}
+
+func (s *fileStore) Overrides(group string) ([]Override, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	state, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := []Override{}
+	for _, override := range state.Overrides {
+		if group == "" || override.Group == group {
+			overrides = append(overrides, override)
+		}
+	}
+
+	return overrides, nil
+}
+
+func (s *fileStore) Close() error {
+	return nil
+}
+
+// lockPath returns the path of the sidecar file locked while the state file
+// is updated.
+func (s *fileStore) lockPath() string {
+	return s.path + ".lock"
+}
+
+func (s *fileStore) load() (*fileState, error) {
+	state := &fileState{}
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// write replaces the state file atomically so that a crash doesn't leave
+// a broken file.
+func (s *fileStore) write(state *fileState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), ".slackduty-state-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// trim keeps the latest records up to the limit per group.
+func trim(records []Record, limit int) []Record {
+	counts := map[string]int{}
+	keep := make([]bool, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		group := records[i].Group
+		if counts[group] < limit {
+			keep[i] = true
+			counts[group]++
+		}
+	}
+
+	result := []Record{}
+	for i, record := range records {
+		if keep[i] {
+			result = append(result, record)
+		}
+	}
+
+	return result
+}
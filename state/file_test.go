@@ -0,0 +1,288 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+)
+
+func newTestFileStore(t *testing.T, limit int) (Store, func()) {
+	dir, err := ioutil.TempDir("", "slackduty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewFileStore(filepath.Join(dir, "state", "state.json"), limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func TestFileStore_History(t *testing.T) {
+	store, cleanup := newTestFileStore(t, 2)
+	defer cleanup()
+
+	base := time.Date(2026, 10, 13, 3, 0, 0, 0, time.UTC)
+	records := []Record{
+		{RunID: "run1", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base, Outcome: OutcomeSuccess},
+		{RunID: "run2", Group: "db", Usergroup: "handle:db-oncall", Timestamp: base.Add(time.Hour), Outcome: OutcomeSuccess},
+		{RunID: "run3", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base.Add(2 * time.Hour), Outcome: OutcomeFailed},
+		{RunID: "run4", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base.Add(3 * time.Hour), Outcome: OutcomeSuccess},
+	}
+
+	for _, record := range records {
+		if err := store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tcs := map[string]struct {
+		key  string
+		want []string
+	}{
+		"group trimmed to limit": {"web", []string{"run3", "run4"}},
+		"usergroup":              {"handle:db-oncall", []string{"run2"}},
+		"unknown":                {"unknown", []string{}},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := store.History(tc.key)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("record count doesn't match got: %d want: %d", len(got), len(tc.want))
+			}
+
+			for i, record := range got {
+				if record.RunID != tc.want[i] {
+					t.Fatalf("record doesn't match got: %s want: %s", record.RunID, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFileStore_SharedPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "slackduty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "state.json")
+	stores := make([]Store, 2)
+	for i := range stores {
+		if stores[i], err = NewFileStore(path, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const n = 50
+	errs := make(chan error, len(stores)*n)
+	wg := &sync.WaitGroup{}
+	for i, store := range stores {
+		wg.Add(1)
+		go func(i int, store Store) {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				errs <- store.Save(Record{RunID: fmt.Sprintf("run%d-%d", i, j), Group: "web", Outcome: OutcomeSuccess})
+			}
+		}(i, store)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := stores[0].History("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := len(stores) * n; len(got) != want {
+		t.Fatalf("records are lost got: %d want: %d", len(got), want)
+	}
+}
+
+func TestAt(t *testing.T) {
+	store, cleanup := newTestFileStore(t, 0)
+	defer cleanup()
+
+	base := time.Date(2026, 10, 13, 3, 0, 0, 0, time.UTC)
+	records := []Record{
+		{RunID: "run1", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base, Outcome: OutcomeSuccess, Members: []slackduty.Member{{ID: "U1"}}},
+		{RunID: "run2", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base.Add(time.Hour), Outcome: OutcomeFailed},
+		{RunID: "run3", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base.Add(2 * time.Hour), Outcome: OutcomeSuccess, Members: []slackduty.Member{{ID: "U2"}}},
+	}
+
+	for _, record := range records {
+		if err := store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tcs := map[string]struct {
+		at   time.Time
+		want string
+	}{
+		"before any record":      {base.Add(-time.Minute), ""},
+		"after first success":    {base.Add(90 * time.Minute), "run1"},
+		"after the last success": {base.Add(3 * time.Hour), "run3"},
+	}
+
+	for n, tc := range tcs {
+		t.Run(n, func(t *testing.T) {
+			got, err := At(store, "web", tc.at)
+			if err != nil {
+				t.Fatalf("test %s error: %v", n, err)
+			}
+
+			if tc.want == "" {
+				if len(got) != 0 {
+					t.Fatalf("no record should be found got: %v", got)
+				}
+				return
+			}
+
+			if len(got) != 1 || got[0].RunID != tc.want {
+				t.Fatalf("record doesn't match got: %v want: %s", got, tc.want)
+			}
+		})
+	}
+
+	last, err := Last(store, "web", "", "handle:web-oncall")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last == nil || last.RunID != "run3" {
+		t.Fatalf("last record doesn't match got: %v want: run3", last)
+	}
+}
+
+func TestRollbackSnapshot(t *testing.T) {
+	store, cleanup := newTestFileStore(t, 0)
+	defer cleanup()
+
+	base := time.Date(2026, 10, 13, 3, 0, 0, 0, time.UTC)
+	records := []Record{
+		{RunID: "run1", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base, Outcome: OutcomeSuccess},
+		{RunID: "run2", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base.Add(time.Hour), Outcome: OutcomeSuccess},
+		{RunID: "run3", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base.Add(2 * time.Hour), Outcome: OutcomeFailed},
+		{RunID: "run4", Group: "web", Usergroup: "handle:web-oncall", Timestamp: base.Add(3 * time.Hour), Outcome: OutcomeSuccess},
+	}
+
+	for _, record := range records {
+		if err := store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous, err := Previous(store, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(previous) != 1 || previous[0].RunID != "run2" {
+		t.Fatalf("previous record doesn't match got: %v want: run2", previous)
+	}
+
+	run, err := Run(store, "web", "run1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(run) != 1 || run[0].RunID != "run1" {
+		t.Fatalf("record of the run doesn't match got: %v want: run1", run)
+	}
+
+	if _, err := Run(store, "web", "run3"); err == nil {
+		t.Fatal("failed run should not be restored")
+	}
+}
+
+func TestFileStore_Pause(t *testing.T) {
+	store, cleanup := newTestFileStore(t, 0)
+	defer cleanup()
+
+	if err := store.Pause(Pause{Group: "web", RunID: "run1", Reason: "rollback"}); err != nil {
+		t.Fatal(err)
+	}
+
+	pause, err := store.Paused("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pause == nil || pause.RunID != "run1" {
+		t.Fatalf("pause doesn't match got: %v want: run1", pause)
+	}
+
+	if pause, _ := store.Paused("db"); pause != nil {
+		t.Fatalf("group should not be paused got: %v", pause)
+	}
+
+	if err := store.Resume("web"); err != nil {
+		t.Fatal(err)
+	}
+
+	if pause, _ := store.Paused("web"); pause != nil {
+		t.Fatalf("group should be resumed got: %v", pause)
+	}
+}
+
+func TestFileStore_Overrides(t *testing.T) {
+	store, cleanup := newTestFileStore(t, 0)
+	defer cleanup()
+
+	// Note: The overrides are pruned by the given time, not by the wall clock.
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	overrides := []Override{
+		{ID: "o1", Group: "web", Action: ActionAdd, User: "email:alice@example.com", Until: now.Add(-time.Hour)},
+		{ID: "o2", Group: "web", Action: ActionRemove, User: "id:U1", Until: now.Add(time.Hour)},
+		{ID: "o3", Group: "db", Action: ActionAdd, User: "id:U2", Until: now.Add(time.Hour)},
+	}
+
+	for _, override := range overrides {
+		if err := store.AddOverride(override, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := store.Overrides("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Note: o1 is removed when o2 is added because it is already expired.
+	if len(got) != 1 || got[0].ID != "o2" {
+		t.Fatalf("overrides don't match got: %v want: [o2]", got)
+	}
+
+	if err := store.DeleteOverride("o3"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := store.Overrides(""); len(got) != 1 {
+		t.Fatalf("override count doesn't match got: %d want: 1", len(got))
+	}
+
+	if err := store.DeleteOverride("unknown"); err == nil {
+		t.Fatal("deleting unknown override should fail")
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+// +build !windows
+
+package state
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes the exclusive lock of the file so that the processes
+// sharing the state file don't overwrite the changes of each other. The
+// returned function releases the lock.
+func lockFile(path string) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() error {
+		defer f.Close()
+		return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}, nil
+}
@@ -0,0 +1,259 @@
+package state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/KeisukeYamashita/slackduty/slackduty"
+)
+
+// Outcome is the result of synchronizing a usergroup.
+type Outcome string
+
+const (
+	// OutcomeSuccess means the usergroup is updated with the members.
+	OutcomeSuccess Outcome = "success"
+	// OutcomeFailed means the sync failed and the usergroup might not be updated.
+	OutcomeFailed Outcome = "failed"
+	// OutcomeSkipped means the usergroup is left as it was(e.g. no member was resolved).
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeHeld means the usergroup is left as it was because the members
+	// violated the guard of the group.
+	OutcomeHeld Outcome = "held"
+	// OutcomeCleared means the usergroup is disabled because no member was
+	// resolved. Slack doesn't allow a usergroup without members.
+	OutcomeCleared Outcome = "cleared"
+	// OutcomeDisabled means the usergroup is disabled because it is outside
+	// of the configured hours.
+	OutcomeDisabled Outcome = "disabled"
+	// OutcomeRolledBack means the usergroup is restored to the members of a
+	// previous record.
+	OutcomeRolledBack Outcome = "rolled_back"
+)
+
+// Applied reports whether the members of the record are applied to the
+// usergroup.
+func (o Outcome) Applied() bool {
+	return o == OutcomeSuccess || o == OutcomeRolledBack
+}
+
+// Record is the result of synchronizing a usergroup of the group in a
+// single run. Breakdown is the number of members resolved from each source.
+type Record struct {
+	RunID     string             `json:"run_id"`
+	Group     string             `json:"group"`
+	Workspace string             `json:"workspace"`
+	Usergroup string             `json:"usergroup"`
+	Timestamp time.Time          `json:"timestamp"`
+	Outcome   Outcome            `json:"outcome"`
+	Error     string             `json:"error,omitempty"`
+	Members   []slackduty.Member `json:"members,omitempty"`
+	Breakdown map[string]int     `json:"breakdown,omitempty"`
+}
+
+// Pause stops the automatic sync of the group until it is resumed. RunID is
+// the run that paused the group(e.g. the rollback).
+type Pause struct {
+	Group     string    `json:"group"`
+	RunID     string    `json:"run_id"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Action is what the override does to the computed members.
+type Action string
+
+const (
+	// ActionAdd adds the user to the members.
+	ActionAdd Action = "add"
+	// ActionRemove removes the user from the members.
+	ActionRemove Action = "remove"
+)
+
+// Override adds or removes a user to the computed members of the group until
+// it expires. User is the Slack user selector(e.g. `email:alice@example.com`).
+type Override struct {
+	ID        string    `json:"id"`
+	Group     string    `json:"group"`
+	Action    Action    `json:"action"`
+	User      string    `json:"user"`
+	Until     time.Time `json:"until"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Expired reports whether the override is expired at the time.
+func (o Override) Expired(t time.Time) bool {
+	return !t.Before(o.Until)
+}
+
+// Store is a interface that the state backend should implement
+type Store interface {
+	// Save appends the record to the history.
+	Save(Record) error
+	// History returns the records of the group or the usergroup ordered by
+	// the timestamp.
+	History(string) ([]Record, error)
+	// Pause pauses the automatic sync of the group.
+	Pause(Pause) error
+	// Resume resumes the automatic sync of the group.
+	Resume(string) error
+	// Paused returns the pause of the group or nil if it is not paused.
+	Paused(string) (*Pause, error)
+	// AddOverride saves the override. The overrides expired at the time are
+	// removed.
+	AddOverride(Override, time.Time) error
+	// DeleteOverride deletes the override by the ID.
+	DeleteOverride(string) error
+	// Overrides returns the overrides of the group. All overrides are
+	// returned if the group is empty.
+	Overrides(string) ([]Override, error)
+	Close() error
+}
+
+// NewRunID creates a ID that identifies the run of the sync. It is sortable by
+// the time.
+func NewRunID(t time.Time) string {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return t.UTC().Format("20060102T150405Z")
+	}
+
+	return fmt.Sprintf("%s-%s", t.UTC().Format("20060102T150405Z"), hex.EncodeToString(b))
+}
+
+// NewOverrideID creates a ID that identifies the override.
+func NewOverrideID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Active returns the overrides that are not expired at the time.
+func Active(overrides []Override, t time.Time) []Override {
+	active := []Override{}
+	for _, override := range overrides {
+		if !override.Expired(t) {
+			active = append(active, override)
+		}
+	}
+
+	return active
+}
+
+// Match reports whether the record is of the group or the usergroup.
+func Match(record Record, key string) bool {
+	return record.Group == key || record.Usergroup == key
+}
+
+// At returns the last applied record of each workspace and usergroup of
+// the group at the time. It answers who was in the usergroup at the time.
+func At(store Store, key string, t time.Time) ([]Record, error) {
+	records, err := store.History(key)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := map[string]Record{}
+	for _, record := range records {
+		if !record.Outcome.Applied() || record.Timestamp.After(t) {
+			continue
+		}
+
+		latest[record.Workspace+"/"+record.Usergroup] = record
+	}
+
+	return sortRecords(latest), nil
+}
+
+// Run returns the applied records of the run of the group or the usergroup.
+func Run(store Store, key, runID string) ([]Record, error) {
+	records, err := store.History(key)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]Record{}
+	for _, record := range records {
+		if record.RunID == runID && record.Outcome.Applied() {
+			found[record.Workspace+"/"+record.Usergroup] = record
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no applied record of the run id: %s", runID)
+	}
+
+	return sortRecords(found), nil
+}
+
+// Previous returns the applied record before the last applied one of each
+// workspace and usergroup of the group. It is the snapshot to roll back a bad
+// sync.
+func Previous(store Store, key string) ([]Record, error) {
+	records, err := store.History(key)
+	if err != nil {
+		return nil, err
+	}
+
+	last := map[string]Record{}
+	previous := map[string]Record{}
+	for _, record := range records {
+		if !record.Outcome.Applied() {
+			continue
+		}
+
+		k := record.Workspace + "/" + record.Usergroup
+		if l, ok := last[k]; ok {
+			previous[k] = l
+		}
+		last[k] = record
+	}
+
+	if len(previous) == 0 {
+		return nil, fmt.Errorf("no previous applied record of %s", key)
+	}
+
+	return sortRecords(previous), nil
+}
+
+// sortRecords sorts the records by the workspace and the usergroup.
+func sortRecords(records map[string]Record) []Record {
+	result := []Record{}
+	for _, record := range records {
+		result = append(result, record)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Workspace != result[j].Workspace {
+			return result[i].Workspace < result[j].Workspace
+		}
+		return result[i].Usergroup < result[j].Usergroup
+	})
+
+	return result
+}
+
+// Last returns the last applied record of the usergroup in the workspace.
+// It returns nil if there is no such record.
+func Last(store Store, group, workspace, usergroup string) (*Record, error) {
+	records, err := store.History(group)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.Outcome.Applied() && record.Workspace == workspace && record.Usergroup == usergroup {
+			return &record, nil
+		}
+	}
+
+	return nil, nil
+}